@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey/monkey"
+	"os"
+)
+
+func main() {
+	dot := flag.Bool("dot", false, "print the parse tree as a Graphviz digraph")
+	cluster := flag.Bool("cluster", false, "with -dot, draw every function body in a box of its own")
+	format := flag.Bool("format", false, "print the program as canonical, formatted source")
+	dropComments := flag.Bool("drop-comments", false, "with -format, remove the plain comments instead of failing")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: monkey [flags] [file]\n\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Parses file, or the standard input, and prints the program.\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	filename := "<stdin>"
+	var source []byte
+	var err error
+
+	switch flag.NArg() {
+	case 0:
+		source, err = io.ReadAll(os.Stdin)
+	case 1:
+		filename = flag.Arg(0)
+		source, err = os.ReadFile(filename)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	parser := monkey.NewParser(monkey.NewLexer(string(source)))
+	program := parser.Parse()
+
+	if len(parser.Errors()) > 0 {
+		renderer := monkey.NewDiagnosticRenderer(filename, string(source), monkey.DIAGNOSTIC_FORMAT_PLAIN)
+		renderer.Render(os.Stderr, monkey.ParserDiagnostics(parser))
+		os.Exit(1)
+	}
+
+	if *dot {
+		fmt.Print(monkey.RenderDot(program, monkey.DotOptions{ClusterFunctions: *cluster}))
+		return
+	}
+
+	if *format {
+		formatted, err := monkey.FormatSource(filename, string(source), monkey.FormatOptions{DropComments: *dropComments})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(formatted)
+		return
+	}
+
+	fmt.Println(program.String())
+}
@@ -0,0 +1,122 @@
+package monkey
+
+const (
+	slabFirstChunk = 16
+	slabLastChunk  = 4096
+)
+
+// slab hands out values from chunks allocated together, so that thousands of
+// nodes cost a handful of allocations. Chunks grow from 16 to 4096 values. A
+// nil slab allocates every value on its own.
+type slab[T any] struct {
+	chunk []T
+}
+
+func (slab *slab[T]) alloc(value T) *T {
+	if slab == nil {
+		pointer := new(T)
+		*pointer = value
+		return pointer
+	}
+
+	if len(slab.chunk) == cap(slab.chunk) {
+		size := min(max(2*cap(slab.chunk), slabFirstChunk), slabLastChunk)
+		slab.chunk = make([]T, 0, size)
+	}
+	slab.chunk = append(slab.chunk, value)
+	return &slab.chunk[len(slab.chunk)-1]
+}
+
+// Arena allocates the tokens and nodes of the parsers made by NewArenaParser
+// in slabs, one per type, instead of one by one. The trees are the same as
+// with NewParser, but they are collected by chunks: a chunk stays alive as
+// long as any of its nodes is referenced. Slices, such as the statements of
+// a compound, are still allocated on their own.
+//
+// An arena can be shared by parsers, one after the other, but not
+// concurrently.
+type Arena struct {
+	tokens *slab[Token]
+
+	compounds            *slab[AstCompound]
+	identifiers          *slab[AstIdentifier]
+	letStatements        *slab[AstLetStatement]
+	returnStatements     *slab[AstReturnStatement]
+	importStatements     *slab[AstImportStatement]
+	exportStatements     *slab[AstExportStatement]
+	expressionStatements *slab[AstExpressionStatement]
+	integerLiterals      *slab[AstIntegerLiteral]
+	booleanLiterals      *slab[AstBooleanLiteral]
+	stringLiterals       *slab[AstStringLiteral]
+	prefixExpressions    *slab[AstPrefixExpression]
+	infixExpressions     *slab[AstInfixExpression]
+	groupedExpressions   *slab[AstGroupedExpression]
+	functionCalls        *slab[AstFunctionCall]
+	parameters           *slab[AstParameter]
+	functionDefinitions  *slab[AstFunctionDefinition]
+	functionDeclarations *slab[AstFunctionDeclaration]
+	macroLiterals        *slab[AstMacroLiteral]
+	ifExpressions        *slab[AstIfExpression]
+	spreadExpressions    *slab[AstSpreadExpression]
+	pipeExpressions      *slab[AstPipeExpression]
+	rangeExpressions     *slab[AstRangeExpression]
+	wildcardPatterns     *slab[AstWildcardPattern]
+	identifierPatterns   *slab[AstIdentifierPattern]
+	literalPatterns      *slab[AstLiteralPattern]
+	arrayPatterns        *slab[AstArrayPattern]
+	restPatterns         *slab[AstRestPattern]
+	hashPatternPairs     *slab[AstHashPatternPair]
+	hashPatterns         *slab[AstHashPattern]
+	matchArms            *slab[AstMatchArm]
+	matchExpressions     *slab[AstMatchExpression]
+	namedTypes           *slab[AstNamedType]
+	arrayTypes           *slab[AstArrayType]
+	functionTypes        *slab[AstFunctionType]
+	docComments          *slab[AstDocComment]
+}
+
+// heapArena has no slabs, so it allocates everything on its own. It is used
+// by the lexers and parsers made without an arena.
+var heapArena = &Arena{}
+
+func NewArena() *Arena {
+	return &Arena{
+		tokens: &slab[Token]{},
+
+		compounds:            &slab[AstCompound]{},
+		identifiers:          &slab[AstIdentifier]{},
+		letStatements:        &slab[AstLetStatement]{},
+		returnStatements:     &slab[AstReturnStatement]{},
+		importStatements:     &slab[AstImportStatement]{},
+		exportStatements:     &slab[AstExportStatement]{},
+		expressionStatements: &slab[AstExpressionStatement]{},
+		integerLiterals:      &slab[AstIntegerLiteral]{},
+		booleanLiterals:      &slab[AstBooleanLiteral]{},
+		stringLiterals:       &slab[AstStringLiteral]{},
+		prefixExpressions:    &slab[AstPrefixExpression]{},
+		infixExpressions:     &slab[AstInfixExpression]{},
+		groupedExpressions:   &slab[AstGroupedExpression]{},
+		functionCalls:        &slab[AstFunctionCall]{},
+		parameters:           &slab[AstParameter]{},
+		functionDefinitions:  &slab[AstFunctionDefinition]{},
+		functionDeclarations: &slab[AstFunctionDeclaration]{},
+		macroLiterals:        &slab[AstMacroLiteral]{},
+		ifExpressions:        &slab[AstIfExpression]{},
+		spreadExpressions:    &slab[AstSpreadExpression]{},
+		pipeExpressions:      &slab[AstPipeExpression]{},
+		rangeExpressions:     &slab[AstRangeExpression]{},
+		wildcardPatterns:     &slab[AstWildcardPattern]{},
+		identifierPatterns:   &slab[AstIdentifierPattern]{},
+		literalPatterns:      &slab[AstLiteralPattern]{},
+		arrayPatterns:        &slab[AstArrayPattern]{},
+		restPatterns:         &slab[AstRestPattern]{},
+		hashPatternPairs:     &slab[AstHashPatternPair]{},
+		hashPatterns:         &slab[AstHashPattern]{},
+		matchArms:            &slab[AstMatchArm]{},
+		matchExpressions:     &slab[AstMatchExpression]{},
+		namedTypes:           &slab[AstNamedType]{},
+		arrayTypes:           &slab[AstArrayType]{},
+		functionTypes:        &slab[AstFunctionType]{},
+		docComments:          &slab[AstDocComment]{},
+	}
+}
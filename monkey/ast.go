@@ -1,10 +1,19 @@
 package monkey
 
-import "bytes"
+import (
+	"bytes"
+	"strings"
+)
 
 type AstNode interface {
 	TokenLiteral() string
 	String() string
+	Pos() Position // where the node's first token starts
+	End() Position // right after the node's last token
+}
+
+func tokenEnd(token *Token) Position {
+	return TokenSpan(token).End
 }
 
 type AstStatement interface {
@@ -18,7 +27,9 @@ type AstExpression interface {
 }
 
 type AstCompound struct {
+	Token      *Token // "{", nil for a program
 	Statements []AstStatement
+	Close      *Token // "}", nil for a program
 }
 
 func (compound *AstCompound) TokenLiteral() string {
@@ -36,6 +47,24 @@ func (compound *AstCompound) String() string {
 
 	return out.String()
 }
+func (compound *AstCompound) Pos() Position {
+	if compound.Token != nil {
+		return compound.Token.Position
+	}
+	if len(compound.Statements) > 0 {
+		return compound.Statements[0].Pos()
+	}
+	return Position{Offset: 0, Line: 1, Column: 1}
+}
+func (compound *AstCompound) End() Position {
+	if compound.Close != nil {
+		return tokenEnd(compound.Close)
+	}
+	if len(compound.Statements) > 0 {
+		return compound.Statements[len(compound.Statements)-1].End()
+	}
+	return compound.Pos()
+}
 
 type AstIdentifier struct {
 	Token *Token // identifier name
@@ -49,11 +78,20 @@ func (identifier *AstIdentifier) TokenLiteral() string {
 func (identifier *AstIdentifier) String() string {
 	return identifier.TokenLiteral()
 }
+func (identifier *AstIdentifier) Pos() Position {
+	return identifier.Token.Position
+}
+func (identifier *AstIdentifier) End() Position {
+	return tokenEnd(identifier.Token)
+}
 
 type AstLetStatement struct {
 	Token      *Token // "let"
 	Identifier *AstIdentifier
+	Pattern    AstPattern // set instead of Identifier when destructuring
+	Type       AstType    // optional annotation
 	Value      AstExpression
+	Doc        *AstDocComment // optional
 }
 
 func (let *AstLetStatement) statement()           {}
@@ -62,13 +100,31 @@ func (let *AstLetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(let.TokenLiteral() + " ")
-	out.WriteString(let.Identifier.String())
+	if let.Pattern != nil {
+		out.WriteString(let.Pattern.String())
+	} else {
+		out.WriteString(let.Identifier.String())
+	}
+	if let.Type != nil {
+		out.WriteString(": " + let.Type.String())
+	}
 	out.WriteString(" = ")
-	out.WriteString(let.Value.String())
+	if let.Value != nil {
+		out.WriteString(let.Value.String())
+	}
 	out.WriteString(";")
 
 	return out.String()
 }
+func (let *AstLetStatement) Pos() Position {
+	return let.Token.Position
+}
+func (let *AstLetStatement) End() Position {
+	if let.Value == nil {
+		return tokenEnd(let.Token)
+	}
+	return let.Value.End()
+}
 
 type AstReturnStatement struct {
 	Token *Token // "return"
@@ -82,12 +138,75 @@ func (returnStatement *AstReturnStatement) TokenLiteral() string {
 func (returnStatement *AstReturnStatement) String() string {
 	var out bytes.Buffer
 
-	out.WriteString(returnStatement.TokenLiteral() + " ")
-	out.WriteString(returnStatement.Value.String())
+	out.WriteString(returnStatement.TokenLiteral())
+	if returnStatement.Value != nil {
+		out.WriteString(" " + returnStatement.Value.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+func (returnStatement *AstReturnStatement) Pos() Position {
+	return returnStatement.Token.Position
+}
+func (returnStatement *AstReturnStatement) End() Position {
+	if returnStatement.Value == nil {
+		return tokenEnd(returnStatement.Token)
+	}
+	return returnStatement.Value.End()
+}
+
+type AstImportStatement struct {
+	Token *Token // "import"
+	Path  *AstStringLiteral
+	Alias *AstIdentifier
+}
+
+func (importStatement *AstImportStatement) statement() {}
+func (importStatement *AstImportStatement) TokenLiteral() string {
+	return importStatement.Token.Literal
+}
+func (importStatement *AstImportStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(importStatement.TokenLiteral() + " ")
+	out.WriteString(importStatement.Path.String())
+	if importStatement.Alias != nil {
+		out.WriteString(" as ")
+		out.WriteString(importStatement.Alias.String())
+	}
 	out.WriteString(";")
 
 	return out.String()
 }
+func (importStatement *AstImportStatement) Pos() Position {
+	return importStatement.Token.Position
+}
+func (importStatement *AstImportStatement) End() Position {
+	if importStatement.Alias != nil {
+		return importStatement.Alias.End()
+	}
+	return importStatement.Path.End()
+}
+
+type AstExportStatement struct {
+	Token     *Token // "export"
+	Statement AstStatement
+}
+
+func (exportStatement *AstExportStatement) statement() {}
+func (exportStatement *AstExportStatement) TokenLiteral() string {
+	return exportStatement.Token.Literal
+}
+func (exportStatement *AstExportStatement) String() string {
+	return exportStatement.TokenLiteral() + " " + exportStatement.Statement.String()
+}
+func (exportStatement *AstExportStatement) Pos() Position {
+	return exportStatement.Token.Position
+}
+func (exportStatement *AstExportStatement) End() Position {
+	return exportStatement.Statement.End()
+}
 
 type AstExpressionStatement struct {
 	Token      *Token // first token
@@ -99,8 +218,20 @@ func (expression *AstExpressionStatement) TokenLiteral() string {
 	return expression.Expression.TokenLiteral()
 }
 func (expression *AstExpressionStatement) String() string {
+	if expression.Expression == nil {
+		return ";"
+	}
 	return expression.Expression.String() + ";"
 }
+func (expression *AstExpressionStatement) Pos() Position {
+	return expression.Token.Position
+}
+func (expression *AstExpressionStatement) End() Position {
+	if expression.Expression == nil {
+		return tokenEnd(expression.Token)
+	}
+	return expression.Expression.End()
+}
 
 type AstIntegerLiteral struct {
 	Token *Token // the integer string
@@ -114,6 +245,12 @@ func (integer *AstIntegerLiteral) TokenLiteral() string {
 func (integer *AstIntegerLiteral) String() string {
 	return integer.TokenLiteral()
 }
+func (integer *AstIntegerLiteral) Pos() Position {
+	return integer.Token.Position
+}
+func (integer *AstIntegerLiteral) End() Position {
+	return tokenEnd(integer.Token)
+}
 
 type AstBooleanLiteral struct {
 	Token *Token // "true" or "false"
@@ -127,6 +264,12 @@ func (boolean *AstBooleanLiteral) TokenLiteral() string {
 func (boolean *AstBooleanLiteral) String() string {
 	return boolean.TokenLiteral()
 }
+func (boolean *AstBooleanLiteral) Pos() Position {
+	return boolean.Token.Position
+}
+func (boolean *AstBooleanLiteral) End() Position {
+	return tokenEnd(boolean.Token)
+}
 
 type AstPrefixExpression struct {
 	Token    *Token // Operator token
@@ -148,6 +291,12 @@ func (prefix *AstPrefixExpression) String() string {
 
 	return out.String()
 }
+func (prefix *AstPrefixExpression) Pos() Position {
+	return prefix.Token.Position
+}
+func (prefix *AstPrefixExpression) End() Position {
+	return prefix.Right.End()
+}
 
 type AstInfixExpression struct {
 	Token    *Token // Operator token
@@ -171,11 +320,50 @@ func (infix *AstInfixExpression) String() string {
 
 	return out.String()
 }
+func (infix *AstInfixExpression) Pos() Position {
+	return infix.Left.Pos()
+}
+func (infix *AstInfixExpression) End() Position {
+	return infix.Right.End()
+}
+
+type AstGroupedExpression struct {
+	Token      *Token // "("
+	Expression AstExpression
+	Close      *Token // ")"
+}
+
+func (grouped *AstGroupedExpression) expression() {}
+func (grouped *AstGroupedExpression) TokenLiteral() string {
+	return grouped.Token.Literal
+}
+
+// String adds no parentheses around an expression already printed within its
+// own, so grouping does not change how an expression prints.
+func (grouped *AstGroupedExpression) String() string {
+	switch grouped.Expression.(type) {
+	case *AstPrefixExpression,
+		*AstInfixExpression,
+		*AstPipeExpression,
+		*AstRangeExpression,
+		*AstGroupedExpression:
+		return grouped.Expression.String()
+	default:
+		return "(" + grouped.Expression.String() + ")"
+	}
+}
+func (grouped *AstGroupedExpression) Pos() Position {
+	return grouped.Token.Position
+}
+func (grouped *AstGroupedExpression) End() Position {
+	return tokenEnd(grouped.Close)
+}
 
 type AstFunctionCall struct {
 	Token      *Token // the identifier token
 	Identifier *AstIdentifier
 	Arguments  []AstExpression
+	Close      *Token // ")"
 }
 
 func (functionCall *AstFunctionCall) expression() {}
@@ -198,10 +386,81 @@ func (functionCall *AstFunctionCall) String() string {
 	return out.String()
 }
 
+// Pos is the start of the identifier, or of the first argument when it comes
+// first, as in the desugared `a |> f(b)`, so that the span of a call always
+// covers its children.
+func (functionCall *AstFunctionCall) Pos() Position {
+	pos := functionCall.Token.Position
+	if len(functionCall.Arguments) > 0 {
+		if first := functionCall.Arguments[0].Pos(); first.Offset < pos.Offset {
+			pos = first
+		}
+	}
+	return pos
+}
+
+// End is the end of ")", or for a call without one, like the desugared `a |> f`,
+// whichever of the identifier and the last argument ends later.
+func (functionCall *AstFunctionCall) End() Position {
+	if functionCall.Close != nil {
+		return tokenEnd(functionCall.Close)
+	}
+
+	end := functionCall.Identifier.End()
+	if count := len(functionCall.Arguments); count > 0 {
+		if last := functionCall.Arguments[count-1].End(); last.Offset > end.Offset {
+			end = last
+		}
+	}
+	return end
+}
+
+type AstParameter struct {
+	Token      *Token // the identifier or "..."
+	Identifier *AstIdentifier
+	Type       AstType // optional annotation
+	Default    AstExpression
+	Rest       bool
+}
+
+func (param *AstParameter) TokenLiteral() string {
+	return param.Token.Literal
+}
+func (param *AstParameter) String() string {
+	var out bytes.Buffer
+
+	if param.Rest {
+		out.WriteString("...")
+	}
+	out.WriteString(param.Identifier.String())
+	if param.Type != nil {
+		out.WriteString(": " + param.Type.String())
+	}
+	if param.Default != nil {
+		out.WriteString(" = ")
+		out.WriteString(param.Default.String())
+	}
+
+	return out.String()
+}
+func (param *AstParameter) Pos() Position {
+	return param.Token.Position
+}
+func (param *AstParameter) End() Position {
+	if param.Default != nil {
+		return param.Default.End()
+	}
+	if param.Type != nil {
+		return param.Type.End()
+	}
+	return param.Identifier.End()
+}
+
 type AstFunctionDefinition struct {
-	Token  *Token // "fn"
-	Params []*AstIdentifier
-	Body   *AstCompound
+	Token      *Token // "fn"
+	Params     []*AstParameter
+	ReturnType AstType // optional annotation
+	Body       *AstCompound
 }
 
 func (functionDefinition *AstFunctionDefinition) expression() {}
@@ -213,14 +472,611 @@ func (functionDefinition *AstFunctionDefinition) String() string {
 
 	out.WriteString(functionDefinition.TokenLiteral() + " (")
 	for index, param := range functionDefinition.Params {
-		out.WriteString(param.Value)
+		out.WriteString(param.String())
 		if index < len(functionDefinition.Params)-1 {
 			out.WriteString(", ")
 		}
 	}
-	out.WriteString(") { ")
+	out.WriteString(")")
+	if functionDefinition.ReturnType != nil {
+		out.WriteString(" -> " + functionDefinition.ReturnType.String())
+	}
+	out.WriteString(" { ")
 	out.WriteString(functionDefinition.Body.String())
 	out.WriteString(" }")
 
 	return out.String()
 }
+func (functionDefinition *AstFunctionDefinition) Pos() Position {
+	return functionDefinition.Token.Position
+}
+func (functionDefinition *AstFunctionDefinition) End() Position {
+	return functionDefinition.Body.End()
+}
+
+type AstFunctionDeclaration struct {
+	Token    *Token // "fn"
+	Name     *AstIdentifier
+	Function *AstFunctionDefinition
+	Doc      *AstDocComment // optional
+}
+
+func (declaration *AstFunctionDeclaration) statement() {}
+func (declaration *AstFunctionDeclaration) TokenLiteral() string {
+	return declaration.Token.Literal
+}
+func (declaration *AstFunctionDeclaration) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(declaration.TokenLiteral() + " ")
+	out.WriteString(declaration.Name.String())
+	out.WriteString("(")
+	for index, param := range declaration.Function.Params {
+		out.WriteString(param.String())
+		if index < len(declaration.Function.Params)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString(")")
+	if declaration.Function.ReturnType != nil {
+		out.WriteString(" -> " + declaration.Function.ReturnType.String())
+	}
+	out.WriteString(" { ")
+	out.WriteString(declaration.Function.Body.String())
+	out.WriteString(" }")
+
+	return out.String()
+}
+func (declaration *AstFunctionDeclaration) Pos() Position {
+	return declaration.Token.Position
+}
+func (declaration *AstFunctionDeclaration) End() Position {
+	return declaration.Function.End()
+}
+
+type AstMacroLiteral struct {
+	Token  *Token // "macro"
+	Params []*AstIdentifier
+	Body   *AstCompound
+}
+
+func (macro *AstMacroLiteral) expression() {}
+func (macro *AstMacroLiteral) TokenLiteral() string {
+	return macro.Token.Literal
+}
+func (macro *AstMacroLiteral) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(macro.TokenLiteral() + " (")
+	for index, param := range macro.Params {
+		out.WriteString(param.String())
+		if index < len(macro.Params)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString(") { ")
+	out.WriteString(macro.Body.String())
+	out.WriteString(" }")
+
+	return out.String()
+}
+func (macro *AstMacroLiteral) Pos() Position {
+	return macro.Token.Position
+}
+func (macro *AstMacroLiteral) End() Position {
+	return macro.Body.End()
+}
+
+type AstIfExpression struct {
+	Token       *Token // "if"
+	Condition   AstExpression
+	Consequence *AstCompound
+	Alternative *AstCompound
+}
+
+func (ifExpression *AstIfExpression) expression() {}
+func (ifExpression *AstIfExpression) TokenLiteral() string {
+	return ifExpression.Token.Literal
+}
+func (ifExpression *AstIfExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ifExpression.TokenLiteral() + " (")
+	out.WriteString(ifExpression.Condition.String())
+	out.WriteString(") { ")
+	out.WriteString(ifExpression.Consequence.String())
+	out.WriteString(" }")
+	if ifExpression.Alternative != nil {
+		out.WriteString(" else { ")
+		out.WriteString(ifExpression.Alternative.String())
+		out.WriteString(" }")
+	}
+
+	return out.String()
+}
+func (ifExpression *AstIfExpression) Pos() Position {
+	return ifExpression.Token.Position
+}
+func (ifExpression *AstIfExpression) End() Position {
+	if ifExpression.Alternative != nil {
+		return ifExpression.Alternative.End()
+	}
+	return ifExpression.Consequence.End()
+}
+
+type AstSpreadExpression struct {
+	Token *Token // "..."
+	Value AstExpression
+}
+
+func (spread *AstSpreadExpression) expression() {}
+func (spread *AstSpreadExpression) TokenLiteral() string {
+	return spread.Token.Literal
+}
+func (spread *AstSpreadExpression) String() string {
+	return spread.TokenLiteral() + spread.Value.String()
+}
+func (spread *AstSpreadExpression) Pos() Position {
+	return spread.Token.Position
+}
+func (spread *AstSpreadExpression) End() Position {
+	return spread.Value.End()
+}
+
+type AstPipeExpression struct {
+	Token *Token // "|>"
+	Left  AstExpression
+	Right AstExpression
+}
+
+func (pipe *AstPipeExpression) expression() {}
+func (pipe *AstPipeExpression) TokenLiteral() string {
+	return pipe.Token.Literal
+}
+func (pipe *AstPipeExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(pipe.Left.String())
+	out.WriteString(" " + pipe.Token.Literal + " ")
+	out.WriteString(pipe.Right.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+func (pipe *AstPipeExpression) Pos() Position {
+	return pipe.Left.Pos()
+}
+func (pipe *AstPipeExpression) End() Position {
+	return pipe.Right.End()
+}
+
+// Desugar rewrites `a |> f(b)` into `f(a, b)` and `a |> f` into `f(a)`. The
+// left operand is kept as is, so a chain desugars one stage at a time, and the
+// call spans the whole pipe. It returns nil when the right operand is neither a
+// call nor an identifier.
+func (pipe *AstPipeExpression) Desugar() *AstFunctionCall {
+	switch right := pipe.Right.(type) {
+	case *AstFunctionCall:
+		arguments := []AstExpression{pipe.Left}
+		arguments = append(arguments, right.Arguments...)
+		return &AstFunctionCall{
+			Token:      right.Token,
+			Identifier: right.Identifier,
+			Arguments:  arguments,
+			Close:      right.Close,
+		}
+	case *AstIdentifier:
+		return &AstFunctionCall{
+			Token:      right.Token,
+			Identifier: right,
+			Arguments:  []AstExpression{pipe.Left},
+		}
+	default:
+		return nil
+	}
+}
+
+type AstRangeExpression struct {
+	Token     *Token // ".." or "..="
+	Start     AstExpression
+	Stop      AstExpression
+	Inclusive bool
+}
+
+func (rangeExpression *AstRangeExpression) expression() {}
+func (rangeExpression *AstRangeExpression) TokenLiteral() string {
+	return rangeExpression.Token.Literal
+}
+func (rangeExpression *AstRangeExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(rangeExpression.Start.String())
+	if rangeExpression.Inclusive {
+		out.WriteString("..=")
+	} else {
+		out.WriteString("..")
+	}
+	out.WriteString(rangeExpression.Stop.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+func (rangeExpression *AstRangeExpression) Pos() Position {
+	return rangeExpression.Start.Pos()
+}
+func (rangeExpression *AstRangeExpression) End() Position {
+	return rangeExpression.Stop.End()
+}
+
+type AstStringLiteral struct {
+	Token *Token // the string contents, without quotes
+	Value string
+}
+
+func (str *AstStringLiteral) expression() {}
+func (str *AstStringLiteral) TokenLiteral() string {
+	return str.Token.Literal
+}
+func (str *AstStringLiteral) String() string {
+	return "\"" + str.Value + "\""
+}
+func (str *AstStringLiteral) Pos() Position {
+	return str.Token.Position
+}
+func (str *AstStringLiteral) End() Position {
+	return tokenEnd(str.Token)
+}
+
+type AstPattern interface {
+	AstNode
+	pattern()
+}
+
+type AstWildcardPattern struct {
+	Token *Token // "_"
+}
+
+func (wildcard *AstWildcardPattern) pattern() {}
+func (wildcard *AstWildcardPattern) TokenLiteral() string {
+	return wildcard.Token.Literal
+}
+func (wildcard *AstWildcardPattern) String() string {
+	return wildcard.TokenLiteral()
+}
+func (wildcard *AstWildcardPattern) Pos() Position {
+	return wildcard.Token.Position
+}
+func (wildcard *AstWildcardPattern) End() Position {
+	return tokenEnd(wildcard.Token)
+}
+
+type AstIdentifierPattern struct {
+	Token      *Token // identifier name
+	Identifier *AstIdentifier
+}
+
+func (identifier *AstIdentifierPattern) pattern() {}
+func (identifier *AstIdentifierPattern) TokenLiteral() string {
+	return identifier.Token.Literal
+}
+func (identifier *AstIdentifierPattern) String() string {
+	return identifier.Identifier.String()
+}
+func (identifier *AstIdentifierPattern) Pos() Position {
+	return identifier.Token.Position
+}
+func (identifier *AstIdentifierPattern) End() Position {
+	return identifier.Identifier.End()
+}
+
+type AstLiteralPattern struct {
+	Token *Token // first token of the literal
+	Value AstExpression
+}
+
+func (literal *AstLiteralPattern) pattern() {}
+func (literal *AstLiteralPattern) TokenLiteral() string {
+	return literal.Token.Literal
+}
+func (literal *AstLiteralPattern) String() string {
+	// negative numbers are written without the prefix expression parentheses
+	if prefix, ok := literal.Value.(*AstPrefixExpression); ok {
+		return prefix.Operator + prefix.Right.String()
+	}
+	return literal.Value.String()
+}
+func (literal *AstLiteralPattern) Pos() Position {
+	return literal.Token.Position
+}
+func (literal *AstLiteralPattern) End() Position {
+	return literal.Value.End()
+}
+
+type AstArrayPattern struct {
+	Token    *Token // "["
+	Elements []AstPattern
+	Close    *Token // "]"
+}
+
+func (array *AstArrayPattern) pattern() {}
+func (array *AstArrayPattern) TokenLiteral() string {
+	return array.Token.Literal
+}
+func (array *AstArrayPattern) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("[")
+	for index, element := range array.Elements {
+		out.WriteString(element.String())
+		if index < len(array.Elements)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString("]")
+
+	return out.String()
+}
+func (array *AstArrayPattern) Pos() Position {
+	return array.Token.Position
+}
+func (array *AstArrayPattern) End() Position {
+	return tokenEnd(array.Close)
+}
+
+type AstRestPattern struct {
+	Token      *Token // "..."
+	Identifier *AstIdentifier
+}
+
+func (rest *AstRestPattern) pattern() {}
+func (rest *AstRestPattern) TokenLiteral() string {
+	return rest.Token.Literal
+}
+func (rest *AstRestPattern) String() string {
+	return rest.TokenLiteral() + rest.Identifier.String()
+}
+func (rest *AstRestPattern) Pos() Position {
+	return rest.Token.Position
+}
+func (rest *AstRestPattern) End() Position {
+	return rest.Identifier.End()
+}
+
+type AstHashPatternPair struct {
+	Token *Token // first token of the key
+	Key   AstExpression
+	Value AstPattern
+}
+
+func (pair *AstHashPatternPair) TokenLiteral() string {
+	return pair.Token.Literal
+}
+func (pair *AstHashPatternPair) String() string {
+	key, isIdentifier := pair.Key.(*AstIdentifier)
+	value, isIdentifierPattern := pair.Value.(*AstIdentifierPattern)
+	if isIdentifier && isIdentifierPattern &&
+		key.Value == value.Identifier.Value {
+		return key.String()
+	}
+
+	return pair.Key.String() + ": " + pair.Value.String()
+}
+func (pair *AstHashPatternPair) Pos() Position {
+	return pair.Key.Pos()
+}
+func (pair *AstHashPatternPair) End() Position {
+	return pair.Value.End()
+}
+
+type AstHashPattern struct {
+	Token *Token // "{"
+	Pairs []*AstHashPatternPair
+	Close *Token // "}"
+}
+
+func (hash *AstHashPattern) pattern() {}
+func (hash *AstHashPattern) TokenLiteral() string {
+	return hash.Token.Literal
+}
+func (hash *AstHashPattern) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("{")
+	for index, pair := range hash.Pairs {
+		out.WriteString(pair.String())
+		if index < len(hash.Pairs)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+func (hash *AstHashPattern) Pos() Position {
+	return hash.Token.Position
+}
+func (hash *AstHashPattern) End() Position {
+	return tokenEnd(hash.Close)
+}
+
+type AstMatchArm struct {
+	Token   *Token // first token of the pattern
+	Pattern AstPattern
+	Guard   AstExpression
+	Body    AstExpression
+}
+
+func (arm *AstMatchArm) TokenLiteral() string {
+	return arm.Token.Literal
+}
+func (arm *AstMatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(arm.Pattern.String())
+	if arm.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(arm.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(arm.Body.String())
+
+	return out.String()
+}
+func (arm *AstMatchArm) Pos() Position {
+	return arm.Pattern.Pos()
+}
+func (arm *AstMatchArm) End() Position {
+	return arm.Body.End()
+}
+
+type AstMatchExpression struct {
+	Token   *Token // "match"
+	Subject AstExpression
+	Arms    []*AstMatchArm
+	Close   *Token // "}"
+}
+
+func (match *AstMatchExpression) expression() {}
+func (match *AstMatchExpression) TokenLiteral() string {
+	return match.Token.Literal
+}
+func (match *AstMatchExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(match.TokenLiteral() + " (")
+	out.WriteString(match.Subject.String())
+	out.WriteString(") { ")
+	for index, arm := range match.Arms {
+		out.WriteString(arm.String())
+		if index < len(match.Arms)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString(" }")
+
+	return out.String()
+}
+func (match *AstMatchExpression) Pos() Position {
+	return match.Token.Position
+}
+func (match *AstMatchExpression) End() Position {
+	return tokenEnd(match.Close)
+}
+
+type AstType interface {
+	AstNode
+	typeNode()
+}
+
+type AstNamedType struct {
+	Token *Token // the type name
+	Name  string
+}
+
+func (named *AstNamedType) typeNode() {}
+func (named *AstNamedType) TokenLiteral() string {
+	return named.Token.Literal
+}
+func (named *AstNamedType) String() string {
+	return named.Name
+}
+func (named *AstNamedType) Pos() Position {
+	return named.Token.Position
+}
+func (named *AstNamedType) End() Position {
+	return tokenEnd(named.Token)
+}
+
+type AstArrayType struct {
+	Token   *Token // "["
+	Element AstType
+	Close   *Token // "]"
+}
+
+func (array *AstArrayType) typeNode() {}
+func (array *AstArrayType) TokenLiteral() string {
+	return array.Token.Literal
+}
+func (array *AstArrayType) String() string {
+	return "[" + array.Element.String() + "]"
+}
+func (array *AstArrayType) Pos() Position {
+	return array.Token.Position
+}
+func (array *AstArrayType) End() Position {
+	return tokenEnd(array.Close)
+}
+
+type AstFunctionType struct {
+	Token  *Token // "fn"
+	Params []AstType
+	Return AstType
+	Close  *Token // ")"
+}
+
+func (function *AstFunctionType) typeNode() {}
+func (function *AstFunctionType) TokenLiteral() string {
+	return function.Token.Literal
+}
+func (function *AstFunctionType) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("fn(")
+	for index, param := range function.Params {
+		out.WriteString(param.String())
+		if index < len(function.Params)-1 {
+			out.WriteString(", ")
+		}
+	}
+	out.WriteString(")")
+	if function.Return != nil {
+		out.WriteString(" -> " + function.Return.String())
+	}
+
+	return out.String()
+}
+func (function *AstFunctionType) Pos() Position {
+	return function.Token.Position
+}
+func (function *AstFunctionType) End() Position {
+	if function.Return != nil {
+		return function.Return.End()
+	}
+	return tokenEnd(function.Close)
+}
+
+// AstDocComment holds the consecutive "///" lines written right before a
+// declaration.
+type AstDocComment struct {
+	Tokens []*Token // one per line
+}
+
+func (doc *AstDocComment) TokenLiteral() string {
+	return doc.Tokens[0].Literal
+}
+func (doc *AstDocComment) String() string {
+	lines := []string{}
+	for _, token := range doc.Tokens {
+		lines = append(lines, token.Literal)
+	}
+	return strings.Join(lines, "\n")
+}
+func (doc *AstDocComment) Pos() Position {
+	return doc.Tokens[0].Position
+}
+func (doc *AstDocComment) End() Position {
+	return tokenEnd(doc.Tokens[len(doc.Tokens)-1])
+}
+
+// Text returns the documentation without the leading "///", and without the
+// single space that usually follows it.
+func (doc *AstDocComment) Text() string {
+	lines := []string{}
+	for _, token := range doc.Tokens {
+		line := strings.TrimPrefix(token.Literal, "///")
+		lines = append(lines, strings.TrimPrefix(line, " "))
+	}
+	return strings.Join(lines, "\n")
+}
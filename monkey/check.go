@@ -0,0 +1,543 @@
+package monkey
+
+import "fmt"
+
+type CheckError struct {
+	Token   *Token // where the mismatch was found
+	Message string
+}
+
+func (err *CheckError) Error() string {
+	return err.Message
+}
+
+// The types the checker makes up, unlike annotations, are not written
+// anywhere. They still are nodes, so their tokens are synthetic ones at the
+// zero position, which Pos, Clone and EncodeJson handle like any other.
+func syntheticToken(tokenType TokenType, literal string) *Token {
+	return &Token{Type: tokenType, Literal: literal}
+}
+
+func syntheticNamedType(name string) *AstNamedType {
+	return &AstNamedType{Token: syntheticToken(TOKEN_IDENTIFIER, name), Name: name}
+}
+
+func syntheticArrayType(element AstType) *AstArrayType {
+	return &AstArrayType{
+		Token:   syntheticToken(TOKEN_OPEN_BRACKET, "["),
+		Element: element,
+		Close:   syntheticToken(TOKEN_CLOSE_BRACKET, "]"),
+	}
+}
+
+var (
+	typeInt    = syntheticNamedType("int")
+	typeBool   = syntheticNamedType("bool")
+	typeString = syntheticNamedType("string")
+	typeAny    = syntheticNamedType("any")
+)
+
+type checkScope struct {
+	names  map[string]AstType
+	parent *checkScope
+}
+
+func newCheckScope(parent *checkScope) *checkScope {
+	return &checkScope{names: map[string]AstType{}, parent: parent}
+}
+
+func (scope *checkScope) define(name string, nameType AstType) {
+	scope.names[name] = nameType
+}
+
+func (scope *checkScope) lookup(name string) AstType {
+	for current := scope; current != nil; current = current.parent {
+		if nameType, ok := current.names[name]; ok {
+			return nameType
+		}
+	}
+	return nil
+}
+
+type checker struct {
+	errors []*CheckError
+	// return types of the functions being checked, innermost last
+	returns []AstType
+	// the signatures of the function types made from definitions
+	signatures map[*AstFunctionType]*functionSignature
+}
+
+// functionSignature holds what the type of a function leaves out: how many
+// arguments it requires, and the type of the elements its rest parameter,
+// still the last of the type's parameters, collects.
+type functionSignature struct {
+	required int
+	rest     AstType // nil without a rest parameter
+}
+
+// Check is the static pass for type annotations. Annotated bindings,
+// parameters and return types are checked against the types inferred for
+// literals, operators and calls of annotated functions. Anything unannotated
+// is dynamically typed: its type is unknown (nil) and it is never reported.
+// The only inference across bindings is for an unannotated `let` of a
+// function literal, which takes the type of the function's own annotations.
+// Function declarations are visible to their whole compound, as in
+// ResolveDeclarations.
+func Check(program *AstCompound) []*CheckError {
+	checker := &checker{
+		errors:     []*CheckError{},
+		returns:    []AstType{},
+		signatures: map[*AstFunctionType]*functionSignature{},
+	}
+	checker.checkCompound(program, newCheckScope(nil))
+	return checker.errors
+}
+
+func (checker *checker) error(token *Token, format string, args ...any) {
+	checker.errors = append(checker.errors, &CheckError{
+		Token:   token,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func isAnyType(nameType AstType) bool {
+	named, ok := nameType.(*AstNamedType)
+	return ok && named.Name == typeAny.Name
+}
+
+func isNamedType(nameType AstType, name string) bool {
+	named, ok := nameType.(*AstNamedType)
+	return ok && named.Name == name
+}
+
+// assignable reports whether a value of type value may be used where target
+// is expected. Unknown types and "any" are compatible with everything.
+func assignable(target AstType, value AstType) bool {
+	if target == nil || value == nil || isAnyType(target) || isAnyType(value) {
+		return true
+	}
+
+	switch target := target.(type) {
+	case *AstNamedType:
+		named, ok := value.(*AstNamedType)
+		return ok && named.Name == target.Name
+	case *AstArrayType:
+		array, ok := value.(*AstArrayType)
+		return ok && assignable(target.Element, array.Element)
+	case *AstFunctionType:
+		function, ok := value.(*AstFunctionType)
+		if !ok || len(function.Params) != len(target.Params) {
+			return false
+		}
+		for index, param := range target.Params {
+			if !assignable(function.Params[index], param) {
+				return false
+			}
+		}
+		return assignable(target.Return, function.Return)
+	default:
+		return false
+	}
+}
+
+func describeType(nameType AstType) string {
+	if nameType == nil {
+		return typeAny.Name
+	}
+	return nameType.String()
+}
+
+func (checker *checker) checkType(nameType AstType) {
+	switch nameType := nameType.(type) {
+	case *AstNamedType:
+		switch nameType.Name {
+		case typeInt.Name, typeBool.Name, typeString.Name, typeAny.Name:
+		default:
+			checker.error(nameType.Token, "unknown type %q", nameType.Name)
+		}
+	case *AstArrayType:
+		checker.checkType(nameType.Element)
+	case *AstFunctionType:
+		for _, param := range nameType.Params {
+			checker.checkType(param)
+		}
+		if nameType.Return != nil {
+			checker.checkType(nameType.Return)
+		}
+	}
+}
+
+func (checker *checker) checkCompound(compound *AstCompound, scope *checkScope) AstType {
+	for _, statement := range compound.Statements {
+		if exportStatement, ok := statement.(*AstExportStatement); ok {
+			statement = exportStatement.Statement
+		}
+		if declaration, ok := statement.(*AstFunctionDeclaration); ok {
+			scope.define(declaration.Name.Value, checker.functionType(declaration.Function))
+		}
+	}
+
+	var last AstType
+	for _, statement := range compound.Statements {
+		last = checker.checkStatement(statement, scope)
+	}
+	return last
+}
+
+func (checker *checker) checkStatement(statement AstStatement, scope *checkScope) AstType {
+	switch statement := statement.(type) {
+	case *AstLetStatement:
+		valueType := checker.checkExpression(statement.Value, scope)
+		if statement.Type != nil {
+			checker.checkType(statement.Type)
+		}
+
+		if statement.Pattern != nil {
+			for _, identifier := range collectPatternBindings(statement.Pattern, nil) {
+				scope.define(identifier.Value, nil)
+			}
+			if statement.Type != nil && !assignable(statement.Type, valueType) {
+				checker.error(
+					statement.Token,
+					"cannot assign %s to a pattern of type %s",
+					describeType(valueType),
+					statement.Type.String(),
+				)
+			}
+			return nil
+		}
+
+		name := statement.Identifier.Value
+		if statement.Type == nil {
+			if _, ok := statement.Value.(*AstFunctionDefinition); ok {
+				scope.define(name, valueType)
+			} else {
+				scope.define(name, nil)
+			}
+			return nil
+		}
+
+		if !assignable(statement.Type, valueType) {
+			checker.error(
+				statement.Identifier.Token,
+				"cannot assign %s to %q of type %s",
+				describeType(valueType),
+				name,
+				statement.Type.String(),
+			)
+		}
+		scope.define(name, statement.Type)
+		return nil
+	case *AstReturnStatement:
+		var valueType AstType
+		if statement.Value != nil {
+			valueType = checker.checkExpression(statement.Value, scope)
+		}
+		if len(checker.returns) > 0 {
+			expected := checker.returns[len(checker.returns)-1]
+			if expected != nil && !assignable(expected, valueType) {
+				checker.error(
+					statement.Token,
+					"cannot return %s from a function returning %s",
+					describeType(valueType),
+					expected.String(),
+				)
+			}
+		}
+		return nil
+	case *AstExpressionStatement:
+		return checker.checkExpression(statement.Expression, scope)
+	case *AstFunctionDeclaration:
+		checker.checkFunction(statement.Function, scope)
+		return nil
+	case *AstImportStatement:
+		if statement.Alias != nil {
+			scope.define(statement.Alias.Value, nil)
+		}
+		return nil
+	case *AstExportStatement:
+		return checker.checkStatement(statement.Statement, scope)
+	default:
+		return nil
+	}
+}
+
+func (checker *checker) functionType(function *AstFunctionDefinition) AstType {
+	params := []AstType{}
+	signature := &functionSignature{}
+	for _, param := range function.Params {
+		var paramType AstType = typeAny
+		if param.Type != nil {
+			paramType = param.Type
+		}
+		params = append(params, paramType)
+
+		switch {
+		case param.Rest:
+			signature.rest = typeAny
+			if array, ok := paramType.(*AstArrayType); ok {
+				signature.rest = array.Element
+			}
+		case param.Default == nil:
+			signature.required += 1
+		}
+	}
+
+	var returnType AstType = typeAny
+	if function.ReturnType != nil {
+		returnType = function.ReturnType
+	}
+
+	functionType := &AstFunctionType{
+		Token:  syntheticToken(TOKEN_FUNCTION, "fn"),
+		Params: params,
+		Return: returnType,
+		Close:  syntheticToken(TOKEN_CLOSE_PAREN, ")"),
+	}
+	checker.signatures[functionType] = signature
+	return functionType
+}
+
+// signature returns the signature of a function type, which for a type
+// written as an annotation requires all its parameters.
+func (checker *checker) signature(function *AstFunctionType) *functionSignature {
+	if signature, ok := checker.signatures[function]; ok {
+		return signature
+	}
+	return &functionSignature{required: len(function.Params)}
+}
+
+func (checker *checker) checkFunction(function *AstFunctionDefinition, scope *checkScope) AstType {
+	inner := newCheckScope(scope)
+
+	for _, param := range function.Params {
+		if param.Type != nil {
+			checker.checkType(param.Type)
+		}
+		if param.Default != nil {
+			defaultType := checker.checkExpression(param.Default, scope)
+			if param.Type != nil && !assignable(param.Type, defaultType) {
+				checker.error(
+					param.Token,
+					"cannot use %s as the default of %q of type %s",
+					describeType(defaultType),
+					param.Identifier.Value,
+					param.Type.String(),
+				)
+			}
+		}
+		inner.define(param.Identifier.Value, param.Type)
+	}
+
+	if function.ReturnType != nil {
+		checker.checkType(function.ReturnType)
+	}
+
+	checker.returns = append(checker.returns, function.ReturnType)
+	last := checker.checkCompound(function.Body, inner)
+	checker.returns = checker.returns[:len(checker.returns)-1]
+
+	// the value of the last expression statement is returned implicitly
+	statements := function.Body.Statements
+	if function.ReturnType != nil && len(statements) > 0 {
+		if expressionStatement, ok := statements[len(statements)-1].(*AstExpressionStatement); ok &&
+			!assignable(function.ReturnType, last) {
+			checker.error(
+				expressionStatement.Token,
+				"cannot return %s from a function returning %s",
+				describeType(last),
+				function.ReturnType.String(),
+			)
+		}
+	}
+
+	return checker.functionType(function)
+}
+
+func (checker *checker) checkCall(
+	call *AstFunctionCall,
+	arguments []AstExpression,
+	scope *checkScope,
+) AstType {
+	argumentTypes := []AstType{}
+	spreads := false
+	for _, argument := range arguments {
+		if _, ok := argument.(*AstSpreadExpression); ok {
+			spreads = true
+		}
+		argumentTypes = append(argumentTypes, checker.checkExpression(argument, scope))
+	}
+
+	function, ok := scope.lookup(call.Identifier.Value).(*AstFunctionType)
+	if !ok {
+		return nil
+	}
+
+	signature := checker.signature(function)
+	positional := function.Params
+	if signature.rest != nil {
+		positional = positional[:len(positional)-1]
+	}
+
+	// the number of arguments a spread stands for is only known at run time
+	if !spreads {
+		for index, argumentType := range argumentTypes {
+			expected := signature.rest
+			if index < len(positional) {
+				expected = positional[index]
+			}
+			if expected == nil {
+				break
+			}
+			if !assignable(expected, argumentType) {
+				checker.error(
+					call.Token,
+					"cannot use %s as argument %d of %q, expected %s",
+					describeType(argumentType),
+					index+1,
+					call.Identifier.Value,
+					expected.String(),
+				)
+			}
+		}
+
+		switch {
+		case len(arguments) < signature.required:
+			expected := fmt.Sprint(signature.required)
+			if signature.required < len(positional) || signature.rest != nil {
+				expected = "at least " + expected
+			}
+			checker.error(
+				call.Token,
+				"not enough arguments to %q, expected %s, got %d",
+				call.Identifier.Value,
+				expected,
+				len(arguments),
+			)
+		case signature.rest == nil && len(arguments) > len(positional):
+			checker.error(
+				call.Token,
+				"too many arguments to %q, expected at most %d, got %d",
+				call.Identifier.Value,
+				len(positional),
+				len(arguments),
+			)
+		}
+	}
+
+	if isAnyType(function.Return) {
+		return nil
+	}
+	return function.Return
+}
+
+func (checker *checker) checkOperands(
+	token *Token,
+	operator string,
+	expected AstType,
+	operands ...AstType,
+) {
+	for _, operand := range operands {
+		if !assignable(expected, operand) {
+			checker.error(
+				token,
+				"operator %s expects %s, got %s",
+				operator,
+				expected.String(),
+				describeType(operand),
+			)
+			return
+		}
+	}
+}
+
+func (checker *checker) checkExpression(expression AstExpression, scope *checkScope) AstType {
+	switch expression := expression.(type) {
+	case *AstIntegerLiteral:
+		return typeInt
+	case *AstBooleanLiteral:
+		return typeBool
+	case *AstStringLiteral:
+		return typeString
+	case *AstIdentifier:
+		return scope.lookup(expression.Value)
+	case *AstGroupedExpression:
+		return checker.checkExpression(expression.Expression, scope)
+	case *AstPrefixExpression:
+		right := checker.checkExpression(expression.Right, scope)
+		if expression.Operator == "!" {
+			return typeBool
+		}
+		checker.checkOperands(expression.Token, expression.Operator, typeInt, right)
+		return typeInt
+	case *AstInfixExpression:
+		left := checker.checkExpression(expression.Left, scope)
+		right := checker.checkExpression(expression.Right, scope)
+		switch expression.Operator {
+		case "==", "!=", "in":
+			return typeBool
+		case "<", ">":
+			checker.checkOperands(expression.Token, expression.Operator, typeInt, left, right)
+			return typeBool
+		case "+":
+			if isNamedType(left, typeString.Name) || isNamedType(right, typeString.Name) {
+				checker.checkOperands(expression.Token, expression.Operator, typeString, left, right)
+				return typeString
+			}
+			checker.checkOperands(expression.Token, expression.Operator, typeInt, left, right)
+			if left == nil || right == nil {
+				return nil
+			}
+			return typeInt
+		default:
+			checker.checkOperands(expression.Token, expression.Operator, typeInt, left, right)
+			return typeInt
+		}
+	case *AstRangeExpression:
+		start := checker.checkExpression(expression.Start, scope)
+		end := checker.checkExpression(expression.Stop, scope)
+		checker.checkOperands(expression.Token, expression.Token.Literal, typeInt, start, end)
+		return syntheticArrayType(typeInt)
+	case *AstFunctionCall:
+		return checker.checkCall(expression, expression.Arguments, scope)
+	case *AstPipeExpression:
+		if call := expression.Desugar(); call != nil {
+			return checker.checkCall(call, call.Arguments, scope)
+		}
+		checker.checkExpression(expression.Left, scope)
+		checker.checkExpression(expression.Right, scope)
+		return nil
+	case *AstSpreadExpression:
+		checker.checkExpression(expression.Value, scope)
+		return nil
+	case *AstFunctionDefinition:
+		return checker.checkFunction(expression, scope)
+	case *AstIfExpression:
+		checker.checkExpression(expression.Condition, scope)
+		consequence := checker.checkCompound(expression.Consequence, newCheckScope(scope))
+		if expression.Alternative == nil {
+			return nil
+		}
+		alternative := checker.checkCompound(expression.Alternative, newCheckScope(scope))
+		if consequence != nil && alternative != nil &&
+			consequence.String() == alternative.String() {
+			return consequence
+		}
+		return nil
+	case *AstMatchExpression:
+		checker.checkExpression(expression.Subject, scope)
+		for _, arm := range expression.Arms {
+			armScope := newCheckScope(scope)
+			for _, identifier := range collectPatternBindings(arm.Pattern, nil) {
+				armScope.define(identifier.Value, nil)
+			}
+			if arm.Guard != nil {
+				checker.checkExpression(arm.Guard, armScope)
+			}
+			checker.checkExpression(arm.Body, armScope)
+		}
+		return nil
+	default:
+		return nil
+	}
+}
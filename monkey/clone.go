@@ -0,0 +1,62 @@
+package monkey
+
+import "reflect"
+
+// Clone deep-copies a tree: nodes, slices and tokens. Pointers shared inside
+// the tree, like the token of an expression statement and of its expression,
+// are shared the same way in the copy, but nothing is shared with the
+// original.
+func Clone(node AstNode) AstNode {
+	if node == nil {
+		return nil
+	}
+
+	cloner := &cloner{copies: map[any]reflect.Value{}}
+	cloned, _ := cloner.clone(reflect.ValueOf(node)).Interface().(AstNode)
+	return cloned
+}
+
+type cloner struct {
+	// copies by original pointer
+	copies map[any]reflect.Value
+}
+
+func (cloner *cloner) clone(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		cloned := reflect.New(value.Type()).Elem()
+		cloned.Set(cloner.clone(value.Elem()))
+		return cloned
+	case reflect.Pointer:
+		if value.IsNil() {
+			return value
+		}
+		if cloned, ok := cloner.copies[value.Interface()]; ok {
+			return cloned
+		}
+		cloned := reflect.New(value.Type().Elem())
+		cloner.copies[value.Interface()] = cloned
+		cloned.Elem().Set(cloner.clone(value.Elem()))
+		return cloned
+	case reflect.Struct:
+		cloned := reflect.New(value.Type()).Elem()
+		for index := 0; index < value.NumField(); index++ {
+			cloned.Field(index).Set(cloner.clone(value.Field(index)))
+		}
+		return cloned
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		cloned := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for index := 0; index < value.Len(); index++ {
+			cloned.Index(index).Set(cloner.clone(value.Index(index)))
+		}
+		return cloned
+	default:
+		return value
+	}
+}
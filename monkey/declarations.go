@@ -0,0 +1,43 @@
+package monkey
+
+import "fmt"
+
+// ResolveDeclarations is the resolution pass for function declarations.
+//
+// Every `fn name(params) { body }` statement of a compound is visible to the
+// whole compound, regardless of where it appears, so mutually recursive
+// functions can be written in any order. An evaluator entering a compound
+// (the program or a function body) should call ResolveDeclarations first and
+// bind every returned declaration in the compound's environment before
+// running any of its statements. Each function then closes over that shared
+// environment and can see its siblings.
+//
+// Only the direct statements of the compound are resolved, declarations in
+// nested function bodies are resolved when those bodies are entered. Exported
+// declarations are resolved like any other. Declaring the same name twice in
+// one compound is an error.
+func ResolveDeclarations(compound *AstCompound) ([]*AstFunctionDeclaration, error) {
+	declarations := []*AstFunctionDeclaration{}
+	declared := map[string]bool{}
+
+	for _, statement := range compound.Statements {
+		if exportStatement, ok := statement.(*AstExportStatement); ok {
+			statement = exportStatement.Statement
+		}
+
+		declaration, ok := statement.(*AstFunctionDeclaration)
+		if !ok {
+			continue
+		}
+
+		name := declaration.Name.Value
+		if declared[name] {
+			return nil, fmt.Errorf("function %q is declared more than once", name)
+		}
+		declared[name] = true
+
+		declarations = append(declarations, declaration)
+	}
+
+	return declarations, nil
+}
@@ -0,0 +1,361 @@
+package monkey
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type Severity int
+
+const (
+	SEVERITY_ERROR Severity = iota
+	SEVERITY_WARNING
+	SEVERITY_NOTE
+)
+
+func (severity Severity) String() string {
+	switch severity {
+	case SEVERITY_ERROR:
+		return "error"
+	case SEVERITY_WARNING:
+		return "warning"
+	case SEVERITY_NOTE:
+		return "note"
+	default:
+		return "unknown"
+	}
+}
+
+// Span is the source range [Start, End) a diagnostic points at.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+// TokenSpan returns the span a token covers in its source. String tokens
+// cover their quotes too, and the end of file is an empty span.
+func TokenSpan(token *Token) Span {
+	text := token.Literal
+	switch token.Type {
+	case TOKEN_STRING:
+		text = `"` + token.Literal + `"`
+	case TOKEN_EOF:
+		text = ""
+	}
+
+	end := token.Position
+	for index := 0; index < len(text); index++ {
+		if text[index] == '\n' {
+			end.Line += 1
+			end.Column = 1
+		} else {
+			end.Column += 1
+		}
+	}
+	end.Offset += len(text)
+
+	return Span{Start: token.Position, End: end}
+}
+
+// NodeSpan returns the span a whole node covers, from its first token to its
+// last.
+func NodeSpan(node AstNode) Span {
+	return Span{Start: node.Pos(), End: node.End()}
+}
+
+type DiagnosticLabel struct {
+	Span    Span
+	Message string
+}
+
+// Diagnostic is a problem found in a source file, independent of the pass
+// that found it. The primary span is underlined with `^~~~`, secondary labels
+// with `----`, and notes are printed after the snippet.
+type Diagnostic struct {
+	Severity Severity
+	Message  string
+	Span     Span
+	Label    string // shown next to the primary underline, may be empty
+	Labels   []DiagnosticLabel
+	Notes    []string
+}
+
+func NewDiagnostic(severity Severity, token *Token, format string, args ...any) *Diagnostic {
+	return &Diagnostic{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Span:     TokenSpan(token),
+		Labels:   []DiagnosticLabel{},
+		Notes:    []string{},
+	}
+}
+
+func (diagnostic *Diagnostic) WithLabel(token *Token, format string, args ...any) *Diagnostic {
+	diagnostic.Labels = append(diagnostic.Labels, DiagnosticLabel{
+		Span:    TokenSpan(token),
+		Message: fmt.Sprintf(format, args...),
+	})
+	return diagnostic
+}
+
+func (diagnostic *Diagnostic) WithNote(format string, args ...any) *Diagnostic {
+	diagnostic.Notes = append(diagnostic.Notes, fmt.Sprintf(format, args...))
+	return diagnostic
+}
+
+// ParserDiagnostics converts the errors and then the warnings of a parser.
+func ParserDiagnostics(parser *Parser) []*Diagnostic {
+	diagnostics := []*Diagnostic{}
+	for _, err := range parser.Errors() {
+		diagnostics = append(diagnostics, NewDiagnostic(SEVERITY_ERROR, err.Token, "%s", err.Message))
+	}
+	for _, warning := range parser.Warnings() {
+		diagnostics = append(
+			diagnostics,
+			NewDiagnostic(SEVERITY_WARNING, warning.Token, "%s", warning.Message),
+		)
+	}
+	return diagnostics
+}
+
+func CheckDiagnostics(errors []*CheckError) []*Diagnostic {
+	diagnostics := []*Diagnostic{}
+	for _, err := range errors {
+		diagnostics = append(diagnostics, NewDiagnostic(SEVERITY_ERROR, err.Token, "%s", err.Message))
+	}
+	return diagnostics
+}
+
+type DiagnosticFormat int
+
+const (
+	DIAGNOSTIC_FORMAT_PLAIN DiagnosticFormat = iota
+	DIAGNOSTIC_FORMAT_ANSI
+	DIAGNOSTIC_FORMAT_JSON
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+	ansiCyan   = "\x1b[36m"
+)
+
+type DiagnosticRenderer struct {
+	filename string
+	lines    []string
+	format   DiagnosticFormat
+}
+
+func NewDiagnosticRenderer(filename string, source string, format DiagnosticFormat) *DiagnosticRenderer {
+	lines := strings.Split(source, "\n")
+	for index, line := range lines {
+		// the "\r" of a CRLF line break is not part of the line
+		lines[index] = strings.TrimSuffix(line, "\r")
+	}
+
+	return &DiagnosticRenderer{
+		filename: filename,
+		lines:    lines,
+		format:   format,
+	}
+}
+
+func (renderer *DiagnosticRenderer) Render(writer io.Writer, diagnostics []*Diagnostic) error {
+	if renderer.format == DIAGNOSTIC_FORMAT_JSON {
+		return renderer.renderJson(writer, diagnostics)
+	}
+
+	var out bytes.Buffer
+	for index, diagnostic := range diagnostics {
+		if index > 0 {
+			out.WriteString("\n")
+		}
+		renderer.renderText(&out, diagnostic)
+	}
+
+	_, err := writer.Write(out.Bytes())
+	return err
+}
+
+func (renderer *DiagnosticRenderer) paint(text string, styles ...string) string {
+	if renderer.format != DIAGNOSTIC_FORMAT_ANSI || text == "" {
+		return text
+	}
+	return strings.Join(styles, "") + text + ansiReset
+}
+
+func severityColor(severity Severity) string {
+	switch severity {
+	case SEVERITY_ERROR:
+		return ansiRed
+	case SEVERITY_WARNING:
+		return ansiYellow
+	default:
+		return ansiCyan
+	}
+}
+
+type renderedLabel struct {
+	span    Span
+	message string
+	primary bool
+}
+
+func (renderer *DiagnosticRenderer) renderText(out *bytes.Buffer, diagnostic *Diagnostic) {
+	color := severityColor(diagnostic.Severity)
+	start := diagnostic.Span.Start
+
+	fmt.Fprintf(
+		out,
+		"%s %s %s\n",
+		renderer.paint(fmt.Sprintf("%s:%d:%d:", renderer.filename, start.Line, start.Column), ansiBold),
+		renderer.paint(diagnostic.Severity.String()+":", ansiBold, color),
+		renderer.paint(diagnostic.Message, ansiBold),
+	)
+
+	labels := []renderedLabel{{diagnostic.Span, diagnostic.Label, true}}
+	for _, label := range diagnostic.Labels {
+		labels = append(labels, renderedLabel{label.Span, label.Message, false})
+	}
+	sort.SliceStable(labels, func(i, j int) bool {
+		return labels[i].span.Start.Line < labels[j].span.Start.Line
+	})
+
+	width := 1
+	for _, label := range labels {
+		width = max(width, len(strconv.Itoa(label.span.Start.Line)))
+	}
+	gutter := renderer.paint(strings.Repeat(" ", width)+" |", ansiBold, ansiBlue)
+
+	out.WriteString(gutter + "\n")
+	for index, label := range labels {
+		line := label.span.Start.Line
+		if line < 1 || line > len(renderer.lines) {
+			continue
+		}
+
+		if index == 0 || labels[index-1].span.Start.Line != line {
+			number := renderer.paint(fmt.Sprintf("%*d |", width, line), ansiBold, ansiBlue)
+			out.WriteString(number + " " + renderer.lines[line-1] + "\n")
+		}
+
+		underline := strings.Repeat("-", renderer.underlineWidth(label.span))
+		underlineColor := ansiBlue
+		if label.primary {
+			underline = "^" + strings.Repeat("~", len(underline)-1)
+			underlineColor = color
+		}
+
+		text := underline
+		if label.message != "" {
+			text += " " + label.message
+		}
+
+		out.WriteString(gutter + " " + renderer.indent(label.span.Start) + renderer.paint(text, ansiBold, underlineColor) + "\n")
+	}
+
+	for _, note := range diagnostic.Notes {
+		out.WriteString(renderer.paint(strings.Repeat(" ", width)+" =", ansiBold, ansiBlue))
+		out.WriteString(" " + renderer.paint("note:", ansiBold) + " " + note + "\n")
+	}
+}
+
+// indent reproduces the whitespace in front of position, keeping tabs so
+// the underline lines up with the source line above it.
+func (renderer *DiagnosticRenderer) indent(position Position) string {
+	line := renderer.lines[position.Line-1]
+	prefix := line[:min(max(position.Column-1, 0), len(line))]
+
+	var out strings.Builder
+	for _, character := range prefix {
+		if character == '\t' {
+			out.WriteRune('\t')
+		} else {
+			out.WriteRune(' ')
+		}
+	}
+	return out.String()
+}
+
+// underlineWidth counts the characters of span on its first line. A span
+// that continues onto later lines is underlined up to and including the
+// line break, and an empty span still gets a single caret.
+func (renderer *DiagnosticRenderer) underlineWidth(span Span) int {
+	line := renderer.lines[span.Start.Line-1]
+	from := min(max(span.Start.Column-1, 0), len(line))
+
+	if span.End.Line != span.Start.Line {
+		return utf8.RuneCountInString(line[from:]) + 1
+	}
+
+	to := min(max(span.End.Column-1, from), len(line))
+	return max(utf8.RuneCountInString(line[from:to]), 1)
+}
+
+type jsonPosition struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonSpan struct {
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+type jsonLabel struct {
+	Span    jsonSpan `json:"span"`
+	Message string   `json:"message"`
+}
+
+type jsonDiagnostic struct {
+	File     string      `json:"file"`
+	Severity string      `json:"severity"`
+	Message  string      `json:"message"`
+	Span     jsonSpan    `json:"span"`
+	Label    string      `json:"label,omitempty"`
+	Labels   []jsonLabel `json:"labels"`
+	Notes    []string    `json:"notes"`
+}
+
+func toJsonSpan(span Span) jsonSpan {
+	return jsonSpan{
+		Start: jsonPosition{span.Start.Offset, span.Start.Line, span.Start.Column},
+		End:   jsonPosition{span.End.Offset, span.End.Line, span.End.Column},
+	}
+}
+
+func (renderer *DiagnosticRenderer) renderJson(writer io.Writer, diagnostics []*Diagnostic) error {
+	encoded := []jsonDiagnostic{}
+	for _, diagnostic := range diagnostics {
+		labels := []jsonLabel{}
+		for _, label := range diagnostic.Labels {
+			labels = append(labels, jsonLabel{toJsonSpan(label.Span), label.Message})
+		}
+
+		notes := append([]string{}, diagnostic.Notes...)
+
+		encoded = append(encoded, jsonDiagnostic{
+			File:     renderer.filename,
+			Severity: diagnostic.Severity.String(),
+			Message:  diagnostic.Message,
+			Span:     toJsonSpan(diagnostic.Span),
+			Label:    diagnostic.Label,
+			Labels:   labels,
+			Notes:    notes,
+		})
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(encoded)
+}
@@ -0,0 +1,149 @@
+package monkey
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+type DotOptions struct {
+	// draw the body of every function in a box of its own
+	ClusterFunctions bool
+}
+
+// dotEdgeNames shortens the field names used as edge labels, every other
+// field is used in lower camel case, "Consequence" as "consequence".
+var dotEdgeNames = map[string]string{
+	"Arguments": "args",
+}
+
+// RenderDot renders a tree as a Graphviz digraph. Nodes are labelled with
+// their operator, literal or keyword, and edges with the field of the parent
+// holding the child, such as "left", "body" or "args[0]".
+func RenderDot(node AstNode, options DotOptions) string {
+	var nodes bytes.Buffer
+	var edges bytes.Buffer
+
+	ids := []int{}
+	count := 0
+	indent := "  "
+
+	isFunctionBody := func(cursor *Cursor) bool {
+		switch cursor.Parent().(type) {
+		case *AstFunctionDefinition, *AstMacroLiteral:
+			return cursor.Name() == "Body"
+		}
+		return false
+	}
+
+	Apply(node, func(cursor *Cursor) bool {
+		if options.ClusterFunctions && isFunctionBody(cursor) {
+			fmt.Fprintf(&nodes, "%ssubgraph cluster_%d {\n", indent, count)
+			indent += "  "
+			fmt.Fprintf(&nodes, "%slabel=%s;\n", indent, dotQuote(cursor.Parent().TokenLiteral()))
+		}
+
+		id := count
+		count += 1
+		fmt.Fprintf(&nodes, "%sn%d [label=%s];\n", indent, id, dotQuote(dotLabel(cursor.Node())))
+
+		if len(ids) > 0 {
+			label := dotEdgeNames[cursor.Name()]
+			if label == "" {
+				label = astJsonField(cursor.Name())
+			}
+			if cursor.Index() >= 0 {
+				label += fmt.Sprintf("[%d]", cursor.Index())
+			}
+			fmt.Fprintf(&edges, "  n%d -> n%d [label=%s];\n", ids[len(ids)-1], id, dotQuote(label))
+		}
+
+		ids = append(ids, id)
+		return true
+	}, func(cursor *Cursor) bool {
+		ids = ids[:len(ids)-1]
+
+		if options.ClusterFunctions && isFunctionBody(cursor) {
+			indent = indent[2:]
+			fmt.Fprintf(&nodes, "%s}\n", indent)
+		}
+		return true
+	})
+
+	var out bytes.Buffer
+	out.WriteString("digraph ast {\n")
+	out.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+	out.WriteString("  edge [fontname=\"monospace\", fontsize=10];\n")
+	out.Write(nodes.Bytes())
+	out.Write(edges.Bytes())
+	out.WriteString("}\n")
+
+	return out.String()
+}
+
+func dotLabel(node AstNode) string {
+	switch node := node.(type) {
+	case *AstIdentifier:
+		return node.Value
+	case *AstIntegerLiteral, *AstBooleanLiteral, *AstStringLiteral:
+		return node.String()
+	case *AstPrefixExpression:
+		return node.Operator
+	case *AstInfixExpression:
+		return node.Operator
+	case *AstGroupedExpression:
+		return "( )"
+	case *AstFunctionCall:
+		return "call"
+	case *AstParameter:
+		if node.Rest {
+			return "...param"
+		}
+		return "param"
+	case *AstWildcardPattern, *AstNamedType:
+		return node.String()
+	case *AstDocComment:
+		return node.Text()
+	case *AstCompound:
+		return "block"
+	case *AstExpressionStatement:
+		return "expression"
+	case *AstHashPatternPair:
+		return "pair"
+	case *AstMatchArm:
+		return "arm"
+	case *AstFunctionDeclaration:
+		return "fn declaration"
+	case *AstArrayType:
+		return "[ ]"
+	case *AstFunctionType:
+		return "fn type"
+	case *AstIdentifierPattern, *AstLiteralPattern, *AstArrayPattern, *AstHashPattern:
+		return dotKind(node) + " pattern"
+	default:
+		// keywords and operator tokens: let, return, fn, if, match, |>, .., ...
+		return node.TokenLiteral()
+	}
+}
+
+// dotKind turns the type name of a node into words, "AstArrayPattern" into
+// "array".
+func dotKind(node AstNode) string {
+	name := strings.TrimSuffix(strings.TrimPrefix(reflect.TypeOf(node).Elem().Name(), "Ast"), "Pattern")
+
+	var out strings.Builder
+	for index, character := range name {
+		if unicode.IsUpper(character) && index > 0 {
+			out.WriteRune(' ')
+		}
+		out.WriteRune(unicode.ToLower(character))
+	}
+	return out.String()
+}
+
+func dotQuote(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + replacer.Replace(text) + `"`
+}
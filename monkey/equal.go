@@ -0,0 +1,194 @@
+package monkey
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type EqualOptions struct {
+	// compare the type and literal of tokens, but not where they are
+	IgnorePositions bool
+	// skip tokens altogether, comparing only the values of the nodes
+	IgnoreTokens bool
+}
+
+// AstDifference is the first mismatch found by Diff. Path leads from the
+// roots to the mismatching field, such as
+// "Statements[2].Value.Right.Arguments[1]", and Left and Right are the
+// innermost nodes holding it.
+type AstDifference struct {
+	Path   string
+	Reason string
+	Left   AstNode
+	Right  AstNode
+}
+
+func (difference *AstDifference) String() string {
+	path := difference.Path
+	if path == "" {
+		path = "<root>"
+	}
+
+	return fmt.Sprintf(
+		"%s: %s\n  - %s\n  + %s",
+		path,
+		difference.Reason,
+		describeNode(difference.Left),
+		describeNode(difference.Right),
+	)
+}
+
+func describeNode(node AstNode) string {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return "<nil>"
+	}
+	return node.String()
+}
+
+func Equal(left AstNode, right AstNode, options EqualOptions) bool {
+	return Diff(left, right, options) == nil
+}
+
+// Diff compares two trees field by field, in the order of the struct fields,
+// and returns the first difference, or nil when they are equal. Nil and
+// empty slices are equal.
+func Diff(left AstNode, right AstNode, options EqualOptions) *AstDifference {
+	differ := &differ{options: options, left: left, right: right}
+	return differ.compare("", reflect.ValueOf(&left).Elem(), reflect.ValueOf(&right).Elem())
+}
+
+type differ struct {
+	options EqualOptions
+	// the innermost nodes being compared
+	left  AstNode
+	right AstNode
+}
+
+func joinPath(path string, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func (differ *differ) difference(path string, format string, args ...any) *AstDifference {
+	return &AstDifference{
+		Path:   path,
+		Reason: fmt.Sprintf(format, args...),
+		Left:   differ.left,
+		Right:  differ.right,
+	}
+}
+
+func kindOf(value reflect.Value) string {
+	if value.Kind() == reflect.Pointer && value.Type().Elem().Kind() == reflect.Struct {
+		return astJsonKind(value.Type())
+	}
+	return value.Type().String()
+}
+
+func (differ *differ) compare(path string, left reflect.Value, right reflect.Value) *AstDifference {
+	switch left.Kind() {
+	case reflect.Interface:
+		if left.IsNil() || right.IsNil() {
+			if left.IsNil() != right.IsNil() {
+				return differ.nilDifference(path, left, right)
+			}
+			return nil
+		}
+		if left.Elem().Type() != right.Elem().Type() {
+			return differ.difference(path, "%s vs %s", kindOf(left.Elem()), kindOf(right.Elem()))
+		}
+		return differ.compare(path, left.Elem(), right.Elem())
+	case reflect.Pointer:
+		if left.IsNil() || right.IsNil() {
+			if left.IsNil() != right.IsNil() {
+				return differ.nilDifference(path, left, right)
+			}
+			return nil
+		}
+		if left.Type() == astTokenType {
+			return differ.compareTokens(path, left.Interface().(*Token), right.Interface().(*Token))
+		}
+		return differ.compareNodes(path, left, right)
+	case reflect.Slice:
+		for index := 0; index < min(left.Len(), right.Len()); index++ {
+			difference := differ.compare(fmt.Sprintf("%s[%d]", path, index), left.Index(index), right.Index(index))
+			if difference != nil {
+				return difference
+			}
+		}
+		if left.Len() != right.Len() {
+			return differ.difference(path, "%d elements vs %d", left.Len(), right.Len())
+		}
+		return nil
+	default:
+		if !left.Equal(right) {
+			return differ.difference(path, "%#v vs %#v", left.Interface(), right.Interface())
+		}
+		return nil
+	}
+}
+
+func (differ *differ) nilDifference(path string, left reflect.Value, right reflect.Value) *AstDifference {
+	if left.IsNil() {
+		return differ.difference(path, "missing on the left")
+	}
+	return differ.difference(path, "missing on the right")
+}
+
+func (differ *differ) compareNodes(path string, left reflect.Value, right reflect.Value) *AstDifference {
+	leftNode, leftOk := left.Interface().(AstNode)
+	rightNode, rightOk := right.Interface().(AstNode)
+	if leftOk && rightOk {
+		outerLeft, outerRight := differ.left, differ.right
+		differ.left, differ.right = leftNode, rightNode
+		defer func() { differ.left, differ.right = outerLeft, outerRight }()
+	}
+
+	structure := left.Type().Elem()
+	for index := 0; index < structure.NumField(); index++ {
+		field := structure.Field(index)
+		if field.Type == astTokenType && differ.options.IgnoreTokens {
+			continue
+		}
+
+		difference := differ.compare(
+			joinPath(path, field.Name),
+			left.Elem().Field(index),
+			right.Elem().Field(index),
+		)
+		if difference != nil {
+			return difference
+		}
+	}
+
+	return nil
+}
+
+func (differ *differ) compareTokens(path string, left *Token, right *Token) *AstDifference {
+	if left.Type != right.Type || left.Literal != right.Literal {
+		return differ.difference(
+			path,
+			"token %s %q vs %s %q",
+			GetTokenTypeString(left.Type),
+			left.Literal,
+			GetTokenTypeString(right.Type),
+			right.Literal,
+		)
+	}
+
+	if !differ.options.IgnorePositions && left.Position != right.Position {
+		return differ.difference(
+			path,
+			"token %q at %d:%d vs %d:%d",
+			left.Literal,
+			left.Position.Line,
+			left.Position.Column,
+			right.Position.Line,
+			right.Position.Column,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,675 @@
+package monkey
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type FormatOptions struct {
+	// spaces per indentation level, 2 when zero
+	IndentWidth int
+	// the column lines are wrapped at when possible, 80 when zero
+	LineLength int
+	// lets FormatSource drop the plain comments of the source
+	DropComments bool
+}
+
+// Format prints a tree as canonical Monkey source. Statements go on lines of
+// their own without semicolons, operators only get the parentheses their
+// precedence needs, and calls, parameters, patterns, match arms and blocks
+// that do not fit in the line length are broken one element per line, with a
+// trailing comma. Long operator chains are broken after their operators.
+//
+// Doc comments are kept, and so are single blank lines between statements,
+// read from token positions. Plain "//" comments never reach the tree, see
+// FormatSource.
+func Format(node AstNode, options FormatOptions) string {
+	if options.IndentWidth <= 0 {
+		options.IndentWidth = 2
+	}
+	if options.LineLength <= 0 {
+		options.LineLength = 80
+	}
+
+	printer := &formatPrinter{options: options}
+	printer.print(formatNode(node))
+
+	if program, ok := node.(*AstCompound); ok && program.Token == nil && len(program.Statements) > 0 {
+		printer.out.WriteString("\n")
+	}
+
+	return printer.out.String()
+}
+
+// FormatSource parses and formats source. Plain "//" comments never reach the
+// tree, so rather than losing them silently, it fails on source that has any,
+// unless options.DropComments is set. Errors start with the filename and the
+// position of the problem.
+func FormatSource(filename string, source string, options FormatOptions) (string, error) {
+	parser := NewParser(NewLexer(source))
+	program := parser.Parse()
+	if len(parser.Errors()) > 0 {
+		return "", parseErrors(filename, parser.Errors())
+	}
+
+	if comments := parser.Comments(); len(comments) > 0 && !options.DropComments {
+		more := ""
+		if len(comments) > 1 {
+			more = fmt.Sprintf(" and %d more", len(comments)-1)
+		}
+		return "", fmt.Errorf(
+			"%s:%d:%d: formatting would remove this comment%s",
+			filename,
+			comments[0].Line,
+			comments[0].Column,
+			more,
+		)
+	}
+
+	return Format(program, options), nil
+}
+
+// formatDoc is the layout of the source before lines are wrapped, one of
+// formatText, formatLine, formatGroup, formatNest, formatIfBroken or a
+// []formatDoc of parts printed one after the other.
+type formatDoc any
+
+type formatText string
+
+type formatLine struct {
+	flat  string // printed instead of the line break when the group fits
+	hard  bool   // breaks even when the rest of the group fits
+	blank bool   // leaves an empty line
+}
+
+// formatGroup is printed on one line when it fits, otherwise every line of
+// the group, but not of the groups inside it, is broken.
+type formatGroup []formatDoc
+
+// formatNest indents the lines broken inside it by one level.
+type formatNest []formatDoc
+
+// formatIfBroken is only printed when the enclosing group is broken.
+type formatIfBroken string
+
+var (
+	formatSpace     = formatLine{flat: " "}
+	formatSoftLine  = formatLine{}
+	formatHardLine  = formatLine{hard: true}
+	formatBlankLine = formatLine{hard: true, blank: true}
+)
+
+func formatNode(node AstNode) formatDoc {
+	switch node := node.(type) {
+	case *AstCompound:
+		if node.Token == nil {
+			return formatStatements(node.Statements)
+		}
+		return formatBlock(node)
+	case AstStatement:
+		return formatStatement(node)
+	case AstExpression:
+		return formatExpression(node)
+	case AstPattern:
+		return formatPattern(node)
+	case AstType:
+		return formatText(node.String())
+	case *AstParameter:
+		return formatParameter(node)
+	case *AstMatchArm:
+		return formatMatchArm(node)
+	case *AstHashPatternPair:
+		return formatHashPatternPair(node)
+	case *AstDocComment:
+		return formatDocComment(node)
+	default:
+		panic(fmt.Sprintf("Format: unexpected node type %T", node))
+	}
+}
+
+func formatStatements(statements []AstStatement) formatDoc {
+	parts := []formatDoc{}
+	for index, statement := range statements {
+		if index > 0 {
+			if hasBlankLineBetween(statements[index-1], statement) {
+				parts = append(parts, formatBlankLine)
+			} else {
+				parts = append(parts, formatHardLine)
+			}
+		}
+		parts = append(parts, formatStatement(statement))
+	}
+	return parts
+}
+
+func hasBlankLineBetween(previous AstStatement, next AstStatement) bool {
+	start := next.Pos()
+	if doc := statementDoc(next); doc != nil {
+		start = doc.Pos()
+	}
+	return start.Line-previous.End().Line > 1
+}
+
+func statementDoc(statement AstStatement) *AstDocComment {
+	switch statement := statement.(type) {
+	case *AstLetStatement:
+		return statement.Doc
+	case *AstFunctionDeclaration:
+		return statement.Doc
+	case *AstExportStatement:
+		return statementDoc(statement.Statement)
+	default:
+		return nil
+	}
+}
+
+func formatBlock(block *AstCompound) formatDoc {
+	return formatGroup{formatBlockParts(block)}
+}
+
+// formatBlockParts lays out a block without a group of its own, so that both
+// branches of an if are broken together.
+func formatBlockParts(block *AstCompound) formatDoc {
+	if len(block.Statements) == 0 {
+		return formatText("{}")
+	}
+
+	return []formatDoc{
+		formatText("{"),
+		formatNest{formatSpace, formatStatements(block.Statements)},
+		formatSpace,
+		formatText("}"),
+	}
+}
+
+// formatList lays out comma separated items, all on one line or one per line
+// followed by a comma. Padded lists put spaces inside their delimiters when
+// on one line, as in "{ 1 => a }".
+func formatList(open string, items []formatDoc, close string, padded bool) formatDoc {
+	if len(items) == 0 {
+		return formatText(open + close)
+	}
+
+	line := formatSoftLine
+	if padded {
+		line = formatSpace
+	}
+
+	inner := formatNest{line}
+	for index, item := range items {
+		if index > 0 {
+			inner = append(inner, formatText(","), formatSpace)
+		}
+		inner = append(inner, item)
+	}
+	inner = append(inner, formatIfBroken(","))
+
+	return formatGroup{formatText(open), inner, line, formatText(close)}
+}
+
+func formatDocComment(doc *AstDocComment) formatDoc {
+	parts := []formatDoc{}
+	for _, token := range doc.Tokens {
+		parts = append(parts, formatText(token.Literal), formatHardLine)
+	}
+	return parts
+}
+
+func formatStatement(statement AstStatement) formatDoc {
+	doc := statementDoc(statement)
+	if doc == nil {
+		return formatUndocumentedStatement(statement)
+	}
+	return []formatDoc{formatDocComment(doc), formatUndocumentedStatement(statement)}
+}
+
+func formatUndocumentedStatement(statement AstStatement) formatDoc {
+	switch statement := statement.(type) {
+	case *AstLetStatement:
+		parts := []formatDoc{formatText("let ")}
+		if statement.Pattern != nil {
+			parts = append(parts, formatPattern(statement.Pattern))
+		} else {
+			parts = append(parts, formatText(statement.Identifier.Value))
+		}
+		if statement.Type != nil {
+			parts = append(parts, formatText(": "+statement.Type.String()))
+		}
+		return append(parts, formatText(" = "), formatExpression(statement.Value))
+	case *AstReturnStatement:
+		if statement.Value == nil {
+			return formatText("return")
+		}
+		return []formatDoc{formatText("return "), formatExpression(statement.Value)}
+	case *AstImportStatement:
+		parts := []formatDoc{formatText("import "), formatExpression(statement.Path)}
+		if statement.Alias != nil {
+			parts = append(parts, formatText(" as "+statement.Alias.Value))
+		}
+		return parts
+	case *AstExportStatement:
+		return []formatDoc{formatText("export "), formatUndocumentedStatement(statement.Statement)}
+	case *AstExpressionStatement:
+		if statement.Expression == nil {
+			return formatText("")
+		}
+		return formatExpression(statement.Expression)
+	case *AstFunctionDeclaration:
+		return formatFunction("fn "+statement.Name.Value, statement.Function)
+	default:
+		panic(fmt.Sprintf("Format: unexpected statement type %T", statement))
+	}
+}
+
+func formatFunction(keyword string, function *AstFunctionDefinition) formatDoc {
+	params := []formatDoc{}
+	for _, param := range function.Params {
+		params = append(params, formatParameter(param))
+	}
+
+	parts := []formatDoc{formatText(keyword), formatList("(", params, ")", false)}
+	if function.ReturnType != nil {
+		parts = append(parts, formatText(" -> "+function.ReturnType.String()))
+	}
+	return append(parts, formatText(" "), formatBlock(function.Body))
+}
+
+func formatParameter(param *AstParameter) formatDoc {
+	parts := []formatDoc{}
+	if param.Rest {
+		parts = append(parts, formatText("..."))
+	}
+	parts = append(parts, formatText(param.Identifier.Value))
+	if param.Type != nil {
+		parts = append(parts, formatText(": "+param.Type.String()))
+	}
+	if param.Default != nil {
+		parts = append(parts, formatText(" = "), formatExpression(param.Default))
+	}
+	return parts
+}
+
+// the precedence of literals, calls and everything else that never needs
+// parentheses
+const formatPrecedenceAtom = PRECEDENCE_POWER + 1
+
+func ungroup(expression AstExpression) AstExpression {
+	for {
+		grouped, ok := expression.(*AstGroupedExpression)
+		if !ok {
+			return expression
+		}
+		expression = grouped.Expression
+	}
+}
+
+// binaryParts splits infix, pipe and range expressions into their operands
+// and operator.
+func binaryParts(expression AstExpression) (left AstExpression, operator string, right AstExpression, ok bool) {
+	switch expression := expression.(type) {
+	case *AstInfixExpression:
+		return expression.Left, expression.Operator, expression.Right, true
+	case *AstPipeExpression:
+		return expression.Left, "|>", expression.Right, true
+	case *AstRangeExpression:
+		if expression.Inclusive {
+			return expression.Start, "..=", expression.Stop, true
+		}
+		return expression.Start, "..", expression.Stop, true
+	default:
+		return nil, "", nil, false
+	}
+}
+
+// operatorTypes maps the operators of binary expressions to the tokens their
+// precedence and associativity are looked up by.
+var operatorTypes = map[string]TokenType{
+	"|>":  TOKEN_PIPE,
+	"==":  TOKEN_EQUALS,
+	"!=":  TOKEN_NOT_EQUALS,
+	"<":   TOKEN_LESS_THAN,
+	">":   TOKEN_GREATER_THAN,
+	"in":  TOKEN_IN,
+	"..":  TOKEN_RANGE,
+	"..=": TOKEN_RANGE_INCLUSIVE,
+	"+":   TOKEN_PLUS,
+	"-":   TOKEN_MINUS,
+	"*":   TOKEN_ASTERISK,
+	"/":   TOKEN_SLASH,
+	"**":  TOKEN_POWER,
+}
+
+func operatorType(operator string) TokenType {
+	return operatorTypes[operator]
+}
+
+// expressionPrecedence is the precedence an expression binds with. A prefix
+// operator only binds its own operand, so it is an atom as a right operand.
+func expressionPrecedence(expression AstExpression, right bool) int {
+	switch expression := expression.(type) {
+	case *AstPrefixExpression:
+		if right {
+			return formatPrecedenceAtom
+		}
+		return PRECEDENCE_PREFIX
+	case *AstIntegerLiteral:
+		// negative values, made by rewrites, print as a prefix expression
+		if expression.Value < 0 && !right {
+			return PRECEDENCE_PREFIX
+		}
+		return formatPrecedenceAtom
+	}
+
+	if _, operator, _, ok := binaryParts(expression); ok {
+		return precedences[operatorType(operator)]
+	}
+	return formatPrecedenceAtom
+}
+
+// formatOperand wraps an operand in parentheses when it binds looser than
+// minimum.
+func formatOperand(expression AstExpression, minimum int, right bool) formatDoc {
+	expression = ungroup(expression)
+	if expressionPrecedence(expression, right) < minimum {
+		return []formatDoc{formatText("("), formatExpression(expression), formatText(")")}
+	}
+	return formatExpression(expression)
+}
+
+// formatBinary lays out a chain of operators of the same precedence, such as
+// a + b - c, as one group broken after each operator. Ranges are never
+// broken.
+func formatBinary(expression AstExpression) formatDoc {
+	left, operator, right, _ := binaryParts(expression)
+	tokenType := operatorType(operator)
+	precedence := precedences[tokenType]
+
+	if tokenType == TOKEN_RANGE || tokenType == TOKEN_RANGE_INCLUSIVE {
+		return []formatDoc{
+			formatOperand(left, precedence, false),
+			formatText(operator),
+			formatOperand(right, precedence+1, true),
+		}
+	}
+
+	if associativities[tokenType] == ASSOCIATIVITY_RIGHT {
+		return formatGroup{
+			formatOperand(left, precedence+1, false),
+			formatNest{formatText(" " + operator), formatSpace, formatOperand(right, precedence, true)},
+		}
+	}
+
+	operators := []string{operator}
+	operands := []AstExpression{right}
+	for {
+		innerLeft, innerOperator, innerRight, ok := binaryParts(ungroup(left))
+		innerType := operatorType(innerOperator)
+		if !ok || precedences[innerType] != precedence || innerType == TOKEN_RANGE || innerType == TOKEN_RANGE_INCLUSIVE {
+			break
+		}
+		operators = append(operators, innerOperator)
+		operands = append(operands, innerRight)
+		left = innerLeft
+	}
+
+	rest := formatNest{}
+	for index := len(operands) - 1; index >= 0; index-- {
+		rest = append(rest,
+			formatText(" "+operators[index]),
+			formatSpace,
+			formatOperand(operands[index], precedence+1, true),
+		)
+	}
+	return formatGroup{formatOperand(left, precedence, false), rest}
+}
+
+func formatExpression(expression AstExpression) formatDoc {
+	switch expression := expression.(type) {
+	case *AstIdentifier:
+		return formatText(expression.Value)
+	case *AstIntegerLiteral:
+		return formatText(strconv.FormatInt(expression.Value, 10))
+	case *AstBooleanLiteral:
+		return formatText(strconv.FormatBool(expression.Value))
+	case *AstStringLiteral:
+		return formatText(`"` + expression.Value + `"`)
+	case *AstPrefixExpression:
+		return []formatDoc{
+			formatText(expression.Operator),
+			formatOperand(expression.Right, PRECEDENCE_PREFIX+1, true),
+		}
+	case *AstInfixExpression, *AstPipeExpression, *AstRangeExpression:
+		return formatBinary(expression)
+	case *AstGroupedExpression:
+		return formatExpression(ungroup(expression))
+	case *AstFunctionCall:
+		arguments := []formatDoc{}
+		for _, argument := range expression.Arguments {
+			arguments = append(arguments, formatExpression(argument))
+		}
+		return []formatDoc{
+			formatText(expression.Identifier.Value),
+			formatList("(", arguments, ")", false),
+		}
+	case *AstSpreadExpression:
+		return []formatDoc{formatText("..."), formatExpression(expression.Value)}
+	case *AstFunctionDefinition:
+		return formatFunction("fn", expression)
+	case *AstMacroLiteral:
+		params := []formatDoc{}
+		for _, param := range expression.Params {
+			params = append(params, formatText(param.Value))
+		}
+		return []formatDoc{
+			formatText("macro"),
+			formatList("(", params, ")", false),
+			formatText(" "),
+			formatBlock(expression.Body),
+		}
+	case *AstIfExpression:
+		branches := formatGroup{formatBlockParts(expression.Consequence)}
+		if expression.Alternative != nil {
+			branches = append(branches, formatText(" else "), formatBlockParts(expression.Alternative))
+		}
+		return []formatDoc{
+			formatText("if ("),
+			formatExpression(expression.Condition),
+			formatText(") "),
+			branches,
+		}
+	case *AstMatchExpression:
+		arms := []formatDoc{}
+		for _, arm := range expression.Arms {
+			arms = append(arms, formatMatchArm(arm))
+		}
+		return []formatDoc{
+			formatText("match ("),
+			formatExpression(expression.Subject),
+			formatText(") "),
+			formatList("{", arms, "}", true),
+		}
+	default:
+		panic(fmt.Sprintf("Format: unexpected expression type %T", expression))
+	}
+}
+
+func formatMatchArm(arm *AstMatchArm) formatDoc {
+	parts := []formatDoc{formatPattern(arm.Pattern)}
+	if arm.Guard != nil {
+		parts = append(parts, formatText(" if "), formatExpression(arm.Guard))
+	}
+	return append(parts, formatText(" => "), formatExpression(arm.Body))
+}
+
+func formatPattern(pattern AstPattern) formatDoc {
+	switch pattern := pattern.(type) {
+	case *AstWildcardPattern:
+		return formatText("_")
+	case *AstIdentifierPattern:
+		return formatText(pattern.Identifier.Value)
+	case *AstLiteralPattern:
+		return formatExpression(pattern.Value)
+	case *AstRestPattern:
+		return formatText("..." + pattern.Identifier.Value)
+	case *AstArrayPattern:
+		elements := []formatDoc{}
+		for _, element := range pattern.Elements {
+			elements = append(elements, formatPattern(element))
+		}
+		return formatList("[", elements, "]", false)
+	case *AstHashPattern:
+		pairs := []formatDoc{}
+		for _, pair := range pattern.Pairs {
+			pairs = append(pairs, formatHashPatternPair(pair))
+		}
+		return formatList("{", pairs, "}", false)
+	default:
+		panic(fmt.Sprintf("Format: unexpected pattern type %T", pattern))
+	}
+}
+
+func formatHashPatternPair(pair *AstHashPatternPair) formatDoc {
+	key, isIdentifier := pair.Key.(*AstIdentifier)
+	value, isIdentifierPattern := pair.Value.(*AstIdentifierPattern)
+	if isIdentifier && isIdentifierPattern && key.Value == value.Identifier.Value {
+		return formatText(key.Value)
+	}
+	return []formatDoc{formatExpression(pair.Key), formatText(": "), formatPattern(pair.Value)}
+}
+
+type formatCommand struct {
+	indent int
+	flat   bool
+	doc    formatDoc
+}
+
+type formatPrinter struct {
+	options FormatOptions
+	out     strings.Builder
+	column  int
+	// the indentation is written with the first text of a line, so that empty
+	// lines have no trailing spaces
+	indent      int
+	indentLines bool
+}
+
+func textWidth(text string) int {
+	if index := strings.LastIndexByte(text, '\n'); index >= 0 {
+		text = text[index+1:]
+	}
+	return utf8.RuneCountInString(text)
+}
+
+func (printer *formatPrinter) print(doc formatDoc) {
+	commands := []formatCommand{{indent: 0, flat: false, doc: doc}}
+
+	for len(commands) > 0 {
+		command := commands[len(commands)-1]
+		commands = commands[:len(commands)-1]
+
+		switch doc := command.doc.(type) {
+		case formatText:
+			if doc == "" {
+				continue
+			}
+			if printer.indentLines {
+				printer.out.WriteString(strings.Repeat(" ", printer.indent))
+				printer.indentLines = false
+			}
+			printer.out.WriteString(string(doc))
+			if strings.Contains(string(doc), "\n") {
+				printer.column = textWidth(string(doc))
+			} else {
+				printer.column += textWidth(string(doc))
+			}
+		case formatLine:
+			if command.flat {
+				commands = append(commands, formatCommand{command.indent, true, formatText(doc.flat)})
+				continue
+			}
+			if doc.blank {
+				printer.out.WriteString("\n")
+			}
+			printer.out.WriteString("\n")
+			printer.indent = command.indent
+			printer.indentLines = true
+			printer.column = command.indent
+		case formatIfBroken:
+			if !command.flat {
+				commands = append(commands, formatCommand{command.indent, false, formatText(doc)})
+			}
+		case formatNest:
+			commands = pushFormatDocs(commands, command.indent+printer.options.IndentWidth, command.flat, doc)
+		case formatGroup:
+			flat := command.flat
+			if !flat {
+				remaining := printer.options.LineLength - printer.column
+				flat = fitsOnLine(remaining, formatCommand{command.indent, true, []formatDoc(doc)}, commands)
+			}
+			commands = pushFormatDocs(commands, command.indent, flat, doc)
+		case []formatDoc:
+			commands = pushFormatDocs(commands, command.indent, command.flat, doc)
+		default:
+			panic(fmt.Sprintf("Format: unexpected layout %T", doc))
+		}
+	}
+}
+
+// pushFormatDocs pushes docs on the stack so that the first is printed first.
+func pushFormatDocs(commands []formatCommand, indent int, flat bool, docs []formatDoc) []formatCommand {
+	for index := len(docs) - 1; index >= 0; index-- {
+		commands = append(commands, formatCommand{indent, flat, docs[index]})
+	}
+	return commands
+}
+
+// fitsOnLine reports whether next, printed on one line, and whatever follows
+// it up to the next line break fit in width columns.
+func fitsOnLine(width int, next formatCommand, rest []formatCommand) bool {
+	commands := []formatCommand{next}
+
+	for width >= 0 {
+		if len(commands) == 0 {
+			if len(rest) == 0 {
+				return true
+			}
+			commands = append(commands, rest[len(rest)-1])
+			rest = rest[:len(rest)-1]
+		}
+
+		command := commands[len(commands)-1]
+		commands = commands[:len(commands)-1]
+
+		switch doc := command.doc.(type) {
+		case formatText:
+			// text running over several lines, like a string, only has to
+			// fit up to its first line break
+			if index := strings.IndexByte(string(doc), '\n'); index >= 0 {
+				return width-textWidth(string(doc[:index])) >= 0
+			}
+			width -= textWidth(string(doc))
+		case formatLine:
+			if !command.flat {
+				return true
+			}
+			if doc.hard {
+				return false
+			}
+			width -= textWidth(doc.flat)
+		case formatIfBroken:
+			if !command.flat {
+				width -= textWidth(string(doc))
+			}
+		case formatNest:
+			commands = pushFormatDocs(commands, command.indent, command.flat, doc)
+		case formatGroup:
+			commands = pushFormatDocs(commands, command.indent, command.flat, doc)
+		case []formatDoc:
+			commands = pushFormatDocs(commands, command.indent, command.flat, doc)
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,146 @@
+package monkey
+
+// AstIndex records the relations between the nodes of a tree in side tables,
+// since nodes do not point to their parents. Every node gets an integer ID,
+// its position in the order of Walk: the root is 0, and indexing the same
+// tree again gives the same IDs. The index does not follow changes made to
+// the tree after it was built.
+type AstIndex struct {
+	nodes    []AstNode // by ID
+	ids      map[AstNode]int
+	parents  []int // -1 for the root
+	children [][]int
+	// the spans of the nodes, and of the nodes with their children, which
+	// reach further out for the doc comments before statements
+	spans   []Span
+	extents []Span
+}
+
+func NewAstIndex(program *AstCompound) *AstIndex {
+	index := &AstIndex{ids: map[AstNode]int{}}
+	stack := []int{}
+
+	Inspect(program, func(node AstNode) bool {
+		if node == nil {
+			id := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) > 0 {
+				index.extend(stack[len(stack)-1], index.extents[id])
+			}
+			return true
+		}
+
+		id := len(index.nodes)
+		parent := -1
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1]
+			index.children[parent] = append(index.children[parent], id)
+		}
+
+		span := NodeSpan(node)
+		index.nodes = append(index.nodes, node)
+		index.ids[node] = id
+		index.parents = append(index.parents, parent)
+		index.children = append(index.children, nil)
+		index.spans = append(index.spans, span)
+		index.extents = append(index.extents, span)
+
+		stack = append(stack, id)
+		return true
+	})
+
+	return index
+}
+
+func (index *AstIndex) extend(id int, span Span) {
+	extent := &index.extents[id]
+	if span.Start.Offset < extent.Start.Offset {
+		extent.Start = span.Start
+	}
+	if span.End.Offset > extent.End.Offset {
+		extent.End = span.End
+	}
+}
+
+// Len returns the number of nodes, IDs go from 0 to Len() - 1.
+func (index *AstIndex) Len() int {
+	return len(index.nodes)
+}
+
+func (index *AstIndex) Node(id int) AstNode {
+	return index.nodes[id]
+}
+
+// Id returns the ID of a node, and false for nodes that are not in the tree.
+func (index *AstIndex) Id(node AstNode) (int, bool) {
+	id, ok := index.ids[node]
+	return id, ok
+}
+
+// Parent returns the node holding node, or nil for the root and the nodes
+// that are not in the tree.
+func (index *AstIndex) Parent(node AstNode) AstNode {
+	id, ok := index.ids[node]
+	if !ok || index.parents[id] < 0 {
+		return nil
+	}
+	return index.nodes[index.parents[id]]
+}
+
+// Children returns the nodes held by node, in the order of Walk.
+func (index *AstIndex) Children(node AstNode) []AstNode {
+	id, ok := index.ids[node]
+	if !ok {
+		return nil
+	}
+
+	children := []AstNode{}
+	for _, child := range index.children[id] {
+		children = append(children, index.nodes[child])
+	}
+	return children
+}
+
+// Enclosing returns the closest ancestor of node of type T, such as the
+// *AstFunctionDefinition or the AstStatement a node is in.
+func Enclosing[T AstNode](index *AstIndex, node AstNode) (T, bool) {
+	for parent := index.Parent(node); parent != nil; parent = index.Parent(parent) {
+		if enclosing, ok := parent.(T); ok {
+			return enclosing, true
+		}
+	}
+
+	var none T
+	return none, false
+}
+
+func spanCovers(span Span, offset int) bool {
+	return span.Start.Offset <= offset && offset < span.End.Offset
+}
+
+// NodeAt returns the innermost node whose span, from the start of its first
+// token up to the end of its last, covers the byte offset. It returns nil
+// when no node does, as in the blank lines around a program.
+func (index *AstIndex) NodeAt(offset int) AstNode {
+	found := -1
+
+	for id := 0; id >= 0 && id < len(index.nodes); {
+		if spanCovers(index.spans[id], offset) {
+			found = id
+		}
+
+		next := -1
+		for _, child := range index.children[id] {
+			if spanCovers(index.extents[child], offset) {
+				next = child
+				break
+			}
+		}
+		id = next
+	}
+
+	if found < 0 {
+		return nil
+	}
+	return index.nodes[found]
+}
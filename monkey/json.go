@@ -0,0 +1,418 @@
+package monkey
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// AST_JSON_VERSION is bumped whenever the encoding changes in a way older
+// decoders cannot read, such as a renamed kind or field. Version 2 added the
+// "close" tokens ending the nodes and the "token" of compounds, and renamed the
+// "end" of ranges to "stop".
+const AST_JSON_VERSION = 2
+
+// astJsonKinds lists every node type that can be encoded. The kind of a node
+// is its type name without the "Ast" prefix.
+var astJsonKinds = []AstNode{
+	&AstCompound{},
+	&AstLetStatement{},
+	&AstReturnStatement{},
+	&AstImportStatement{},
+	&AstExportStatement{},
+	&AstExpressionStatement{},
+	&AstFunctionDeclaration{},
+	&AstIdentifier{},
+	&AstIntegerLiteral{},
+	&AstBooleanLiteral{},
+	&AstStringLiteral{},
+	&AstMacroLiteral{},
+	&AstPrefixExpression{},
+	&AstInfixExpression{},
+	&AstGroupedExpression{},
+	&AstFunctionCall{},
+	&AstParameter{},
+	&AstFunctionDefinition{},
+	&AstSpreadExpression{},
+	&AstIfExpression{},
+	&AstPipeExpression{},
+	&AstRangeExpression{},
+	&AstMatchExpression{},
+	&AstMatchArm{},
+	&AstWildcardPattern{},
+	&AstIdentifierPattern{},
+	&AstLiteralPattern{},
+	&AstArrayPattern{},
+	&AstRestPattern{},
+	&AstHashPattern{},
+	&AstHashPatternPair{},
+	&AstNamedType{},
+	&AstArrayType{},
+	&AstFunctionType{},
+	&AstDocComment{},
+}
+
+var (
+	astNodeType       = reflect.TypeFor[AstNode]()
+	astTokenType      = reflect.TypeFor[*Token]()
+	astKindTypes      = map[string]reflect.Type{}
+	astTokenTypeNames = map[string]TokenType{}
+)
+
+func init() {
+	for _, node := range astJsonKinds {
+		nodeType := reflect.TypeOf(node)
+		astKindTypes[astJsonKind(nodeType)] = nodeType.Elem()
+	}
+	for tokenType := TokenType(TOKEN_ILLEGAL); tokenType <= TOKEN_DOC_COMMENT; tokenType++ {
+		astTokenTypeNames[GetTokenTypeString(tokenType)] = tokenType
+	}
+}
+
+func astJsonKind(nodeType reflect.Type) string {
+	return strings.TrimPrefix(nodeType.Elem().Name(), "Ast")
+}
+
+// astJsonField turns a Go field name into its JSON key, "ReturnType" into
+// "returnType".
+func astJsonField(name string) string {
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+type jsonToken struct {
+	Type     string       `json:"type"`
+	Literal  string       `json:"literal"`
+	Position jsonPosition `json:"position"`
+}
+
+type jsonDocument struct {
+	Version int `json:"version"`
+	Root    any `json:"root"`
+}
+
+// EncodeJson encodes a tree as
+//
+//	{"version": 2, "root": <node>}
+//
+// where every node is an object with a "kind", such as "LetStatement", and
+// one key per field of the node's struct, in lower camel case. Tokens are
+// objects with their "type" name, "literal" and "position". Missing optional
+// children are null. AstJsonSchema describes the format in full. As in any
+// JSON document, invalid UTF-8 in literals is replaced by U+FFFD.
+func EncodeJson(node AstNode) ([]byte, error) {
+	root, err := encodeJsonValue(reflect.ValueOf(&node).Elem())
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(map[string]any{"version": AST_JSON_VERSION, "root": root})
+}
+
+func encodeJsonValue(value reflect.Value) (any, error) {
+	switch value.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if value.IsNil() {
+			return nil, nil
+		}
+		if value.Type() == astTokenType {
+			token := value.Interface().(*Token)
+			return jsonToken{
+				Type:     GetTokenTypeString(token.Type),
+				Literal:  token.Literal,
+				Position: jsonPosition{token.Position.Offset, token.Position.Line, token.Position.Column},
+			}, nil
+		}
+		if value.Kind() == reflect.Interface {
+			return encodeJsonValue(value.Elem())
+		}
+		return encodeJsonNode(value)
+	case reflect.Slice:
+		if value.IsNil() {
+			return nil, nil
+		}
+		items := make([]any, value.Len())
+		for index := range items {
+			item, err := encodeJsonValue(value.Index(index))
+			if err != nil {
+				return nil, err
+			}
+			items[index] = item
+		}
+		return items, nil
+	case reflect.String, reflect.Int64, reflect.Bool:
+		return value.Interface(), nil
+	default:
+		return nil, fmt.Errorf("cannot encode a value of type %s", value.Type())
+	}
+}
+
+func encodeJsonNode(value reflect.Value) (any, error) {
+	kind := astJsonKind(value.Type())
+	if _, ok := astKindTypes[kind]; !ok {
+		return nil, fmt.Errorf("cannot encode a node of type %s", value.Type())
+	}
+
+	object := map[string]any{"kind": kind}
+	structure := value.Elem()
+	for index := 0; index < structure.NumField(); index++ {
+		field, err := encodeJsonValue(structure.Field(index))
+		if err != nil {
+			return nil, err
+		}
+		object[astJsonField(structure.Type().Field(index).Name)] = field
+	}
+
+	return object, nil
+}
+
+// DecodeJson reconstructs the tree encoded by EncodeJson. Unknown kinds and
+// fields are errors, as are documents of another version.
+func DecodeJson(data []byte) (AstNode, error) {
+	// the document is parsed once, and the nodes are built from the generic
+	// values, so that decoding takes a time linear in the size of the tree
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var document jsonDocument
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the document")
+	}
+
+	if document.Version != AST_JSON_VERSION {
+		return nil, fmt.Errorf(
+			"unsupported AST version %d, expected %d",
+			document.Version,
+			AST_JSON_VERSION,
+		)
+	}
+
+	root, err := decodeJsonValue(document.Root, astNodeType)
+	if err != nil {
+		return nil, err
+	}
+
+	node, _ := root.Interface().(AstNode)
+	return node, nil
+}
+
+// decodeJsonLeaf decodes a token or a scalar. Leaves are small, so going
+// through encoding/json again costs little and keeps its conversions and
+// errors.
+func decodeJsonLeaf(data any, target any) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, target)
+}
+
+func decodeJsonValue(data any, target reflect.Type) (reflect.Value, error) {
+	if data == nil {
+		return reflect.Zero(target), nil
+	}
+
+	switch {
+	case target == astTokenType:
+		var token jsonToken
+		if err := decodeJsonLeaf(data, &token); err != nil {
+			return reflect.Value{}, err
+		}
+		tokenType, ok := astTokenTypeNames[token.Type]
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("unknown token type %q", token.Type)
+		}
+		return reflect.ValueOf(&Token{
+			Type:     tokenType,
+			Literal:  token.Literal,
+			Position: Position{token.Position.Offset, token.Position.Line, token.Position.Column},
+		}), nil
+	case target.Kind() == reflect.Interface || target.Kind() == reflect.Pointer:
+		node, err := decodeJsonNode(data)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if !node.Type().AssignableTo(target) {
+			return reflect.Value{}, fmt.Errorf(
+				"%s is not %s",
+				astJsonKind(node.Type()),
+				strings.TrimPrefix(strings.TrimPrefix(target.String(), "*"), "monkey.Ast"),
+			)
+		}
+		value := reflect.New(target).Elem()
+		value.Set(node)
+		return value, nil
+	case target.Kind() == reflect.Slice:
+		items, ok := data.([]any)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected an array")
+		}
+		slice := reflect.MakeSlice(target, len(items), len(items))
+		for index, item := range items {
+			value, err := decodeJsonValue(item, target.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			slice.Index(index).Set(value)
+		}
+		return slice, nil
+	default:
+		value := reflect.New(target)
+		if err := decodeJsonLeaf(data, value.Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+		return value.Elem(), nil
+	}
+}
+
+func decodeJsonNode(data any) (reflect.Value, error) {
+	object, ok := data.(map[string]any)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("expected a node object")
+	}
+
+	kind, ok := object["kind"].(string)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("node without a kind")
+	}
+	delete(object, "kind")
+
+	structType, ok := astKindTypes[kind]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("unknown node kind %q", kind)
+	}
+
+	node := reflect.New(structType)
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		key := astJsonField(field.Name)
+
+		value, err := decodeJsonValue(object[key], field.Type)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%s.%s: %w", kind, key, err)
+		}
+		node.Elem().Field(index).Set(value)
+		delete(object, key)
+	}
+
+	for key := range object {
+		return reflect.Value{}, fmt.Errorf("unknown field %q in %s", key, kind)
+	}
+
+	return node, nil
+}
+
+// AstJsonSchema returns the JSON Schema of the documents written by
+// EncodeJson, for services that validate them without this package.
+func AstJsonSchema() []byte {
+	definitions := map[string]any{
+		"Token": map[string]any{
+			"type":                 "object",
+			"required":             []string{"type", "literal", "position"},
+			"additionalProperties": false,
+			"properties": map[string]any{
+				"type":    map[string]any{"enum": tokenTypeNames()},
+				"literal": map[string]any{"type": "string"},
+				"position": map[string]any{
+					"type":                 "object",
+					"required":             []string{"offset", "line", "column"},
+					"additionalProperties": false,
+					"properties": map[string]any{
+						"offset": map[string]any{"type": "integer"},
+						"line":   map[string]any{"type": "integer"},
+						"column": map[string]any{"type": "integer"},
+					},
+				},
+			},
+		},
+	}
+
+	interfaces := map[string]reflect.Type{
+		"Node":       astNodeType,
+		"Statement":  reflect.TypeFor[AstStatement](),
+		"Expression": reflect.TypeFor[AstExpression](),
+		"Pattern":    reflect.TypeFor[AstPattern](),
+		"Type":       reflect.TypeFor[AstType](),
+	}
+	for name, interfaceType := range interfaces {
+		kinds := []any{}
+		for _, node := range astJsonKinds {
+			if reflect.TypeOf(node).Implements(interfaceType) {
+				kinds = append(kinds, map[string]any{"$ref": "#/$defs/" + astJsonKind(reflect.TypeOf(node))})
+			}
+		}
+		definitions[name] = map[string]any{"oneOf": kinds}
+	}
+
+	for _, node := range astJsonKinds {
+		nodeType := reflect.TypeOf(node)
+		kind := astJsonKind(nodeType)
+
+		properties := map[string]any{"kind": map[string]any{"const": kind}}
+		required := []string{"kind"}
+		for index := 0; index < nodeType.Elem().NumField(); index++ {
+			field := nodeType.Elem().Field(index)
+			properties[astJsonField(field.Name)] = astJsonFieldSchema(field.Type)
+			required = append(required, astJsonField(field.Name))
+		}
+
+		definitions[kind] = map[string]any{
+			"type":                 "object",
+			"required":             required,
+			"additionalProperties": false,
+			"properties":           properties,
+		}
+	}
+
+	schema := map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                fmt.Sprintf("Monkey AST, version %d", AST_JSON_VERSION),
+		"type":                 "object",
+		"required":             []string{"version", "root"},
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"version": map[string]any{"const": AST_JSON_VERSION},
+			"root":    map[string]any{"$ref": "#/$defs/Node"},
+		},
+		"$defs": definitions,
+	}
+
+	encoded, _ := json.MarshalIndent(schema, "", "  ")
+	return encoded
+}
+
+func tokenTypeNames() []string {
+	names := []string{}
+	for tokenType := TokenType(TOKEN_ILLEGAL); tokenType <= TOKEN_DOC_COMMENT; tokenType++ {
+		names = append(names, GetTokenTypeString(tokenType))
+	}
+	return names
+}
+
+func astJsonFieldSchema(fieldType reflect.Type) any {
+	nullable := func(schema any) any {
+		return map[string]any{"oneOf": []any{schema, map[string]any{"type": "null"}}}
+	}
+
+	switch {
+	case fieldType == astTokenType:
+		return nullable(map[string]any{"$ref": "#/$defs/Token"})
+	case fieldType.Kind() == reflect.Interface:
+		return nullable(map[string]any{"$ref": "#/$defs/" + strings.TrimPrefix(fieldType.Name(), "Ast")})
+	case fieldType.Kind() == reflect.Pointer:
+		return nullable(map[string]any{"$ref": "#/$defs/" + astJsonKind(fieldType)})
+	case fieldType.Kind() == reflect.Slice:
+		return nullable(map[string]any{"type": "array", "items": astJsonFieldSchema(fieldType.Elem())})
+	case fieldType.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case fieldType.Kind() == reflect.Int64:
+		return map[string]any{"type": "integer"}
+	default:
+		return map[string]any{"type": "boolean"}
+	}
+}
@@ -1,26 +1,121 @@
 package monkey
 
+import "strings"
+
 type Lexer struct {
-	content  string
-	current  byte
-	position int
+	content         string
+	current         byte
+	position        int
+	line            int
+	column          int
+	insertSemicolon bool
+	// the plain comments skipped, which never become tokens
+	comments []Position
+	// where tokens are allocated
+	arena *Arena
 }
 
 func NewLexer(content string) *Lexer {
 	lexer := &Lexer{
 		content:  content,
 		position: 0,
+		line:     1,
+		column:   1,
+		arena:    heapArena,
+	}
+	if len(lexer.content) > 0 {
+		lexer.current = lexer.content[lexer.position]
 	}
-	lexer.current = lexer.content[lexer.position]
 	return lexer
 }
 
+func isWhitespace(character byte) bool {
+	return character == ' ' ||
+		character == '\t' ||
+		character == '\n' ||
+		character == '\r'
+}
+
+// isComment reports whether a comment starts at index, and whether it is a
+// "///" doc comment. Four or more slashes make a plain comment again, so
+// separator lines such as "////////" are not documentation.
+func (lexer *Lexer) isComment(index int) (comment bool, doc bool) {
+	if !strings.HasPrefix(lexer.content[index:], "//") {
+		return false, false
+	}
+	rest := lexer.content[index+2:]
+	return true, strings.HasPrefix(rest, "/") && !strings.HasPrefix(rest, "//")
+}
+
+// commentEnd returns the index of the line break ending the comment at index.
+func (lexer *Lexer) commentEnd(index int) int {
+	end := strings.IndexByte(lexer.content[index:], '\n')
+	if end < 0 {
+		return len(lexer.content)
+	}
+	return index + end
+}
+
 func (lexer *Lexer) skipWhitespaces() {
-	for lexer.current == ' ' ||
-		lexer.current == '\t' ||
-		lexer.current == '\n' ||
-		lexer.current == '\r' {
-		lexer.advance()
+	for lexer.position < len(lexer.content) {
+		if isWhitespace(lexer.current) {
+			if lexer.current == '\n' &&
+				lexer.insertSemicolon &&
+				!lexer.closesOrEndsAfterWhitespace() {
+				return
+			}
+			lexer.advance()
+			continue
+		}
+
+		comment, doc := lexer.isComment(lexer.position)
+		if !comment || doc {
+			return
+		}
+		lexer.comments = append(lexer.comments, Position{
+			Offset: lexer.position,
+			Line:   lexer.line,
+			Column: lexer.column,
+		})
+		for end := lexer.commentEnd(lexer.position); lexer.position < end; {
+			lexer.advance()
+		}
+	}
+}
+
+// Comments returns the positions of the plain "//" comments skipped so far.
+func (lexer *Lexer) Comments() []Position {
+	return lexer.comments
+}
+
+func (lexer *Lexer) closesOrEndsAfterWhitespace() bool {
+	for index := lexer.position; index < len(lexer.content); index++ {
+		if comment, _ := lexer.isComment(index); comment {
+			index = lexer.commentEnd(index)
+			continue
+		}
+		character := lexer.content[index]
+		if !isWhitespace(character) {
+			return character == ')' || character == ']' || character == '}'
+		}
+	}
+	return true
+}
+
+func insertsSemicolon(tokenType TokenType) bool {
+	switch tokenType {
+	case TOKEN_IDENTIFIER,
+		TOKEN_INTEGER,
+		TOKEN_STRING,
+		TOKEN_TRUE,
+		TOKEN_FALSE,
+		TOKEN_RETURN,
+		TOKEN_CLOSE_PAREN,
+		TOKEN_CLOSE_BRACKET,
+		TOKEN_CLOSE_BRACE:
+		return true
+	default:
+		return false
 	}
 }
 
@@ -36,46 +131,103 @@ func isNumeric(character byte) bool {
 }
 
 func (lexer *Lexer) collectIdentifierOrKeyword() *Token {
-	identifier := ""
+	start := lexer.position
 
 	for isAlphanumeric(lexer.current) {
-		identifier = identifier + string(lexer.current)
 		lexer.advance()
 	}
 
+	identifier := lexer.content[start:lexer.position]
+
 	switch identifier {
 	case "fn":
-		return NewToken(TOKEN_FUNCTION, identifier)
+		return lexer.newToken(TOKEN_FUNCTION, identifier)
 	case "let":
-		return NewToken(TOKEN_LET, identifier)
+		return lexer.newToken(TOKEN_LET, identifier)
 	case "true":
-		return NewToken(TOKEN_TRUE, identifier)
+		return lexer.newToken(TOKEN_TRUE, identifier)
 	case "false":
-		return NewToken(TOKEN_FALSE, identifier)
+		return lexer.newToken(TOKEN_FALSE, identifier)
 	case "if":
-		return NewToken(TOKEN_IF, identifier)
+		return lexer.newToken(TOKEN_IF, identifier)
 	case "else":
-		return NewToken(TOKEN_ELSE, identifier)
+		return lexer.newToken(TOKEN_ELSE, identifier)
 	case "return":
-		return NewToken(TOKEN_RETURN, identifier)
+		return lexer.newToken(TOKEN_RETURN, identifier)
+	case "match":
+		return lexer.newToken(TOKEN_MATCH, identifier)
+	case "in":
+		return lexer.newToken(TOKEN_IN, identifier)
+	case "macro":
+		return lexer.newToken(TOKEN_MACRO, identifier)
+	case "import":
+		return lexer.newToken(TOKEN_IMPORT, identifier)
+	case "export":
+		return lexer.newToken(TOKEN_EXPORT, identifier)
+	case "as":
+		return lexer.newToken(TOKEN_AS, identifier)
 	default:
-		return NewToken(TOKEN_IDENTIFIER, identifier)
+		return lexer.newToken(TOKEN_IDENTIFIER, identifier)
 	}
 }
 
 func (lexer *Lexer) collectIntegerLiteral() *Token {
-	literal := ""
+	start := lexer.position
 
+	// A dot never belongs to an integer. Once floats are lexed, a fraction
+	// must only start when the dot is followed by a digit, so that `1..2`
+	// keeps lexing as a range.
 	for isNumeric(lexer.current) {
-		literal = literal + string(lexer.current)
 		lexer.advance()
 	}
 
-	return NewToken(TOKEN_INTEGER, literal)
+	return lexer.newToken(TOKEN_INTEGER, lexer.content[start:lexer.position])
+}
+
+func (lexer *Lexer) collectStringLiteral() *Token {
+	// skip the opening quote
+	lexer.advance()
+	start := lexer.position
+
+	for lexer.current != '"' {
+		if lexer.position >= len(lexer.content) {
+			return lexer.newToken(TOKEN_ILLEGAL, lexer.content[start-1:lexer.position])
+		}
+		lexer.advance()
+	}
+	literal := lexer.content[start:lexer.position]
+	// skip the closing quote
+	lexer.advance()
+
+	return lexer.newToken(TOKEN_STRING, literal)
+}
+
+func (lexer *Lexer) collectDocComment() *Token {
+	start := lexer.position
+	end := lexer.commentEnd(start)
+	for lexer.position < end {
+		lexer.advance()
+	}
+
+	return lexer.newToken(TOKEN_DOC_COMMENT, strings.TrimSuffix(lexer.content[start:end], "\r"))
+}
+
+func (lexer *Lexer) newToken(tokenType TokenType, literal string) *Token {
+	return lexer.arena.tokens.alloc(Token{Type: tokenType, Literal: literal})
 }
 
 func (lexer *Lexer) advance() {
+	if lexer.position < len(lexer.content) {
+		if lexer.current == '\n' {
+			lexer.line += 1
+			lexer.column = 1
+		} else {
+			lexer.column += 1
+		}
+	}
+
 	if lexer.position+1 >= len(lexer.content) {
+		lexer.position = len(lexer.content)
 		lexer.current = 0
 	} else {
 		lexer.position += 1
@@ -90,78 +242,164 @@ func (lexer *Lexer) peek() byte {
 	return lexer.content[lexer.position+1]
 }
 
+// Next returns the next token of the content, inserting semicolons the way Go
+// does: when a line's final token is an identifier, a literal, "return", ")",
+// "]" or "}", the newline ending that line is returned as a TOKEN_SEMICOLON
+// with the literal "\n". No semicolon is inserted when the next line starts
+// with ")", "]" or "}", so closing delimiters may sit on their own line, nor at
+// the end of the content.
 func (lexer *Lexer) Next() *Token {
 	lexer.skipWhitespaces()
 
+	position := Position{
+		Offset: lexer.position,
+		Line:   lexer.line,
+		Column: lexer.column,
+	}
+	token := lexer.next()
+	token.Position = position
+
+	// a doc comment at the end of a line keeps the pending semicolon
+	if token.Type != TOKEN_DOC_COMMENT {
+		lexer.insertSemicolon = insertsSemicolon(token.Type)
+	}
+	return token
+}
+
+func (lexer *Lexer) next() *Token {
+	// the end of the content, not a NUL byte, which is an illegal character
+	if lexer.position >= len(lexer.content) {
+		return lexer.newToken(TOKEN_EOF, "\x00")
+	}
+
 	switch lexer.current {
-	case 0:
-		return NewToken(TOKEN_EOF, string(lexer.current))
+	case '\n':
+		lexer.advance()
+		return lexer.newToken(TOKEN_SEMICOLON, "\n")
 	case '=':
 		current := string(lexer.current)
 		lexer.advance()
 		if lexer.current == '=' {
-			token := NewToken(TOKEN_EQUALS, current+string(lexer.current))
+			token := lexer.newToken(TOKEN_EQUALS, current+string(lexer.current))
+			lexer.advance()
+			return token
+		}
+		if lexer.current == '>' {
+			token := lexer.newToken(TOKEN_FAT_ARROW, current+string(lexer.current))
 			lexer.advance()
 			return token
 		}
-		return NewToken(TOKEN_ASSIGNMENT, current)
+		return lexer.newToken(TOKEN_ASSIGNMENT, current)
 	case '!':
 		current := string(lexer.current)
 		lexer.advance()
 		if lexer.current == '=' {
-			token := NewToken(TOKEN_NOT_EQUALS, current+string(lexer.current))
+			token := lexer.newToken(TOKEN_NOT_EQUALS, current+string(lexer.current))
 			lexer.advance()
 			return token
 		}
-		return NewToken(TOKEN_BANG, current)
+		return lexer.newToken(TOKEN_BANG, current)
 	case '+':
 		current := string(lexer.current)
 		lexer.advance()
-		return NewToken(TOKEN_PLUS, current)
+		return lexer.newToken(TOKEN_PLUS, current)
 	case '-':
 		current := string(lexer.current)
 		lexer.advance()
-		return NewToken(TOKEN_MINUS, current)
+		if lexer.current == '>' {
+			token := lexer.newToken(TOKEN_ARROW, current+string(lexer.current))
+			lexer.advance()
+			return token
+		}
+		return lexer.newToken(TOKEN_MINUS, current)
 	case '*':
 		current := string(lexer.current)
 		lexer.advance()
-		return NewToken(TOKEN_ASTERISK, current)
+		if lexer.current == '*' {
+			token := lexer.newToken(TOKEN_POWER, current+string(lexer.current))
+			lexer.advance()
+			return token
+		}
+		return lexer.newToken(TOKEN_ASTERISK, current)
 	case '/':
+		if _, doc := lexer.isComment(lexer.position); doc {
+			return lexer.collectDocComment()
+		}
 		current := string(lexer.current)
 		lexer.advance()
-		return NewToken(TOKEN_SLASH, current)
+		return lexer.newToken(TOKEN_SLASH, current)
 	case '<':
 		current := string(lexer.current)
 		lexer.advance()
-		return NewToken(TOKEN_LESS_THAN, current)
+		return lexer.newToken(TOKEN_LESS_THAN, current)
 	case '>':
 		current := string(lexer.current)
 		lexer.advance()
-		return NewToken(TOKEN_GREATER_THAN, current)
+		return lexer.newToken(TOKEN_GREATER_THAN, current)
+	case '|':
+		current := string(lexer.current)
+		lexer.advance()
+		if lexer.current == '>' {
+			token := lexer.newToken(TOKEN_PIPE, current+string(lexer.current))
+			lexer.advance()
+			return token
+		}
+		return lexer.newToken(TOKEN_ILLEGAL, current)
 	case ',':
 		current := string(lexer.current)
 		lexer.advance()
-		return NewToken(TOKEN_COMMA, current)
+		return lexer.newToken(TOKEN_COMMA, current)
 	case ';':
 		current := string(lexer.current)
 		lexer.advance()
-		return NewToken(TOKEN_SEMICOLON, current)
+		return lexer.newToken(TOKEN_SEMICOLON, current)
 	case '(':
 		current := string(lexer.current)
 		lexer.advance()
-		return NewToken(TOKEN_OPEN_PAREN, current)
+		return lexer.newToken(TOKEN_OPEN_PAREN, current)
 	case ')':
 		current := string(lexer.current)
 		lexer.advance()
-		return NewToken(TOKEN_CLOSE_PAREN, current)
+		return lexer.newToken(TOKEN_CLOSE_PAREN, current)
 	case '{':
 		current := string(lexer.current)
 		lexer.advance()
-		return NewToken(TOKEN_OPEN_BRACE, current)
+		return lexer.newToken(TOKEN_OPEN_BRACE, current)
 	case '}':
 		current := string(lexer.current)
 		lexer.advance()
-		return NewToken(TOKEN_CLOSE_BRACE, current)
+		return lexer.newToken(TOKEN_CLOSE_BRACE, current)
+	case '[':
+		current := string(lexer.current)
+		lexer.advance()
+		return lexer.newToken(TOKEN_OPEN_BRACKET, current)
+	case ']':
+		current := string(lexer.current)
+		lexer.advance()
+		return lexer.newToken(TOKEN_CLOSE_BRACKET, current)
+	case ':':
+		current := string(lexer.current)
+		lexer.advance()
+		return lexer.newToken(TOKEN_COLON, current)
+	case '.':
+		if lexer.peek() == '.' {
+			lexer.advance()
+			lexer.advance()
+			if lexer.current == '.' {
+				lexer.advance()
+				return lexer.newToken(TOKEN_ELLIPSIS, "...")
+			}
+			if lexer.current == '=' {
+				lexer.advance()
+				return lexer.newToken(TOKEN_RANGE_INCLUSIVE, "..=")
+			}
+			return lexer.newToken(TOKEN_RANGE, "..")
+		}
+		current := string(lexer.current)
+		lexer.advance()
+		return lexer.newToken(TOKEN_ILLEGAL, current)
+	case '"':
+		return lexer.collectStringLiteral()
 	default:
 		if isNumeric(lexer.current) {
 			return lexer.collectIntegerLiteral()
@@ -171,6 +409,6 @@ func (lexer *Lexer) Next() *Token {
 		}
 		current := string(lexer.current)
 		lexer.advance()
-		return NewToken(TOKEN_ILLEGAL, current)
+		return lexer.newToken(TOKEN_ILLEGAL, current)
 	}
 }
@@ -0,0 +1,154 @@
+package monkey
+
+import "fmt"
+
+// DefineMacros is the collection pass of the macro system. It removes every
+// top level `let name = macro(...) { ... };` statement from the program and
+// returns the collected macros by name.
+func DefineMacros(program *AstCompound) map[string]*AstMacroLiteral {
+	macros := map[string]*AstMacroLiteral{}
+	statements := []AstStatement{}
+
+	for _, statement := range program.Statements {
+		letStatement, ok := statement.(*AstLetStatement)
+		if ok && letStatement.Identifier != nil {
+			if macro, ok := letStatement.Value.(*AstMacroLiteral); ok {
+				macros[letStatement.Identifier.Value] = macro
+				continue
+			}
+		}
+		statements = append(statements, statement)
+	}
+
+	program.Statements = statements
+
+	return macros
+}
+
+// ExpandMacros rewrites every call to a collected macro into the AST the macro
+// returns, before any evaluation happens. Macro arguments are passed as
+// unevaluated AST, and the macro body must be a single `quote(...)`
+// expression, optionally returned. Inside the quoted template,
+// `unquote(param)` is replaced with the AST given for that parameter.
+//
+// Arguments are expanded before the call that receives them, the AST produced
+// by an expansion is not expanded again. The program itself is left untouched,
+// the expansion happens on a clone of it.
+func ExpandMacros(
+	program *AstCompound,
+	macros map[string]*AstMacroLiteral,
+) (*AstCompound, error) {
+	var err error
+
+	expanded := Modify(Clone(program), func(node AstNode) AstNode {
+		call, ok := node.(*AstFunctionCall)
+		if !ok || err != nil {
+			return node
+		}
+
+		macro, ok := macros[call.Identifier.Value]
+		if !ok {
+			return node
+		}
+
+		var expansion AstNode
+		expansion, err = expandMacroCall(call, macro)
+		if err != nil {
+			return node
+		}
+		return expansion
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return expanded.(*AstCompound), nil
+}
+
+func macroTemplate(macro *AstMacroLiteral) AstNode {
+	if len(macro.Body.Statements) != 1 {
+		return nil
+	}
+
+	var value AstExpression
+	switch statement := macro.Body.Statements[0].(type) {
+	case *AstExpressionStatement:
+		value = statement.Expression
+	case *AstReturnStatement:
+		value = statement.Value
+	}
+
+	quote, ok := value.(*AstFunctionCall)
+	if !ok || quote.Identifier.Value != "quote" || len(quote.Arguments) != 1 {
+		return nil
+	}
+
+	return quote.Arguments[0]
+}
+
+func expandMacroCall(call *AstFunctionCall, macro *AstMacroLiteral) (AstNode, error) {
+	name := call.Identifier.Value
+
+	if len(call.Arguments) != len(macro.Params) {
+		return nil, fmt.Errorf(
+			"macro %q expects %d arguments, got %d",
+			name,
+			len(macro.Params),
+			len(call.Arguments),
+		)
+	}
+
+	template := macroTemplate(macro)
+	if template == nil {
+		return nil, fmt.Errorf("macro %q must return a quote(...) expression", name)
+	}
+
+	arguments := map[string]AstExpression{}
+	for index, param := range macro.Params {
+		arguments[param.Value] = call.Arguments[index]
+	}
+
+	var err error
+
+	expansion := Modify(Clone(template), func(node AstNode) AstNode {
+		unquote, ok := node.(*AstFunctionCall)
+		if !ok || unquote.Identifier.Value != "unquote" || err != nil {
+			return node
+		}
+
+		if len(unquote.Arguments) != 1 {
+			err = fmt.Errorf("unquote expects 1 argument, got %d", len(unquote.Arguments))
+			return node
+		}
+
+		identifier, ok := unquote.Arguments[0].(*AstIdentifier)
+		if !ok {
+			err = fmt.Errorf(
+				"macro %q can only unquote its parameters, got %s",
+				name,
+				unquote.Arguments[0].String(),
+			)
+			return node
+		}
+
+		argument, ok := arguments[identifier.Value]
+		if !ok {
+			err = fmt.Errorf(
+				"macro %q has no parameter %q to unquote",
+				name,
+				identifier.Value,
+			)
+			return node
+		}
+
+		// every unquote gets its own copy of the argument
+		return Clone(argument)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return expansion, nil
+}
@@ -0,0 +1,125 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+const MODULE_EXTENSION = ".monkey"
+
+type Module struct {
+	Path    string
+	Program *AstCompound
+	Imports []*Module
+	Exports map[string]AstStatement
+}
+
+// ModuleResolver maps import paths to sources and parses them into modules.
+// An import path such as "lib/strings" is read from "lib/strings.monkey" in
+// the resolver's file system. Every module is parsed once and cached, so
+// importing it again, from anywhere, yields the same *Module.
+type ModuleResolver struct {
+	fsys    fs.FS
+	modules map[string]*Module
+	loading []string
+}
+
+func NewModuleResolver(fsys fs.FS) *ModuleResolver {
+	return &ModuleResolver{
+		fsys:    fsys,
+		modules: map[string]*Module{},
+		loading: []string{},
+	}
+}
+
+func (resolver *ModuleResolver) Resolve(path string) (*Module, error) {
+	if !fs.ValidPath(path) {
+		return nil, fmt.Errorf("invalid import path %q", path)
+	}
+
+	if module, ok := resolver.modules[path]; ok {
+		return module, nil
+	}
+
+	for index, loading := range resolver.loading {
+		if loading == path {
+			chain := append([]string{}, resolver.loading[index:]...)
+			chain = append(chain, path)
+			return nil, fmt.Errorf("import cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	source, err := fs.ReadFile(resolver.fsys, path+MODULE_EXTENSION)
+	if err != nil {
+		return nil, fmt.Errorf("cannot import %q: %w", path, err)
+	}
+
+	parser := NewParser(NewLexer(string(source)))
+	program := parser.Parse()
+	if len(parser.Errors()) > 0 {
+		return nil, parseErrors(path, parser.Errors())
+	}
+
+	module := &Module{
+		Path:    path,
+		Program: program,
+		Imports: []*Module{},
+		Exports: map[string]AstStatement{},
+	}
+
+	resolver.loading = append(resolver.loading, path)
+	defer func() {
+		resolver.loading = resolver.loading[:len(resolver.loading)-1]
+	}()
+
+	for _, statement := range program.Statements {
+		switch statement := statement.(type) {
+		case *AstImportStatement:
+			imported, err := resolver.Resolve(statement.Path.Value)
+			if err != nil {
+				return nil, err
+			}
+			module.Imports = append(module.Imports, imported)
+		case *AstExportStatement:
+			for _, name := range exportedNames(statement.Statement) {
+				module.Exports[name] = statement.Statement
+			}
+		}
+	}
+
+	resolver.modules[path] = module
+
+	return module, nil
+}
+
+// parseErrors joins the parse errors of a module, one `path:line:col: message`
+// line each. The *ParseError values stay reachable through errors.As.
+func parseErrors(path string, parseErrors []*ParseError) error {
+	errs := []error{}
+	for _, err := range parseErrors {
+		position := err.Token.Position
+		errs = append(errs, fmt.Errorf("%s:%d:%d: %w", path, position.Line, position.Column, err))
+	}
+	return errors.Join(errs...)
+}
+
+func exportedNames(statement AstStatement) []string {
+	names := []string{}
+
+	switch statement := statement.(type) {
+	case *AstLetStatement:
+		if statement.Pattern != nil {
+			for _, identifier := range collectPatternBindings(statement.Pattern, nil) {
+				names = append(names, identifier.Value)
+			}
+		} else {
+			names = append(names, statement.Identifier.Value)
+		}
+	case *AstFunctionDeclaration:
+		names = append(names, statement.Name.Value)
+	}
+
+	return names
+}
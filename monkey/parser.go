@@ -1,48 +1,118 @@
 package monkey
 
 import (
+	"fmt"
+	"sort"
 	"strconv"
 )
 
 const (
 	PRECEDENCE_LOWEST = iota
+	PRECEDENCE_PIPE
 	PRECEDENCE_EQUALS
 	PRECEDENCE_LESS_GREATER
+	PRECEDENCE_RANGE
 	PRECEDENCE_SUM
 	PRECEDENCE_PRODUCT
 	PRECEDENCE_PREFIX
+	PRECEDENCE_POWER
 )
 
+const MAX_NESTING_DEPTH = 1000
+
 var precedences = map[TokenType]int{
-	TOKEN_EQUALS:       PRECEDENCE_EQUALS,
-	TOKEN_NOT_EQUALS:   PRECEDENCE_EQUALS,
-	TOKEN_LESS_THAN:    PRECEDENCE_LESS_GREATER,
-	TOKEN_GREATER_THAN: PRECEDENCE_LESS_GREATER,
-	TOKEN_PLUS:         PRECEDENCE_SUM,
-	TOKEN_MINUS:        PRECEDENCE_SUM,
-	TOKEN_ASTERISK:     PRECEDENCE_PRODUCT,
-	TOKEN_SLASH:        PRECEDENCE_PRODUCT,
+	TOKEN_PIPE:            PRECEDENCE_PIPE,
+	TOKEN_EQUALS:          PRECEDENCE_EQUALS,
+	TOKEN_NOT_EQUALS:      PRECEDENCE_EQUALS,
+	TOKEN_LESS_THAN:       PRECEDENCE_LESS_GREATER,
+	TOKEN_GREATER_THAN:    PRECEDENCE_LESS_GREATER,
+	TOKEN_IN:              PRECEDENCE_LESS_GREATER,
+	TOKEN_RANGE:           PRECEDENCE_RANGE,
+	TOKEN_RANGE_INCLUSIVE: PRECEDENCE_RANGE,
+	TOKEN_PLUS:            PRECEDENCE_SUM,
+	TOKEN_MINUS:           PRECEDENCE_SUM,
+	TOKEN_ASTERISK:        PRECEDENCE_PRODUCT,
+	TOKEN_SLASH:           PRECEDENCE_PRODUCT,
+	TOKEN_POWER:           PRECEDENCE_POWER,
+}
+
+const (
+	ASSOCIATIVITY_LEFT = iota
+	ASSOCIATIVITY_RIGHT
+)
+
+// operators missing from this table are left associative
+var associativities = map[TokenType]int{
+	TOKEN_POWER: ASSOCIATIVITY_RIGHT,
+}
+
+type ParseError struct {
+	Token   *Token // where the problem was found
+	Message string
+}
+
+func (err *ParseError) Error() string {
+	return err.Message
 }
 
 type Parser struct {
 	tokens   []*Token
 	position int
 	current  *Token
+	errors   []*ParseError
+	warnings []*ParseError
+	// doc comments, by the index of the token following them
+	docs map[int]*AstDocComment
+	// the positions of the plain comments
+	comments []Position
+	// where tokens and nodes are allocated
+	arena *Arena
+
+	depth          int
+	collapseGroups bool
 }
 
 func NewParser(lexer *Lexer) *Parser {
+	return newParser(lexer, heapArena)
+}
+
+// NewArenaParser makes a parser allocating the tokens of lexer, and the nodes
+// it parses, in arena.
+func NewArenaParser(lexer *Lexer, arena *Arena) *Parser {
+	lexer.arena = arena
+	return newParser(lexer, arena)
+}
+
+func newParser(lexer *Lexer, arena *Arena) *Parser {
 	tokens := []*Token{}
+	docs := map[int]*AstDocComment{}
+
+	for {
+		current := lexer.Next()
+		if current.Type == TOKEN_DOC_COMMENT {
+			doc, ok := docs[len(tokens)]
+			if !ok {
+				doc = arena.docComments.alloc(AstDocComment{Tokens: []*Token{}})
+				docs[len(tokens)] = doc
+			}
+			doc.Tokens = append(doc.Tokens, current)
+			continue
+		}
 
-	current := lexer.Next()
-	tokens = append(tokens, current)
-	for current.Type != TOKEN_EOF {
-		current = lexer.Next()
 		tokens = append(tokens, current)
+		if current.Type == TOKEN_EOF {
+			break
+		}
 	}
 
 	parser := &Parser{
 		tokens:   tokens,
 		position: 0,
+		errors:   []*ParseError{},
+		warnings: []*ParseError{},
+		docs:     docs,
+		comments: lexer.Comments(),
+		arena:    arena,
 	}
 	parser.current = parser.tokens[parser.position]
 
@@ -66,20 +136,115 @@ func (parser *Parser) peek() *Token {
 	return parser.tokens[parser.position+1]
 }
 
+// SetCollapseGroups makes the parser drop grouping parentheses, returning the
+// inner expression instead of an AstGroupedExpression.
+func (parser *Parser) SetCollapseGroups(collapse bool) {
+	parser.collapseGroups = collapse
+}
+
+func (parser *Parser) Errors() []*ParseError {
+	return parser.errors
+}
+
+func (parser *Parser) Warnings() []*ParseError {
+	return parser.warnings
+}
+
+// Comments returns the positions of the plain "//" comments of the source,
+// which are not part of the tree.
+func (parser *Parser) Comments() []Position {
+	return parser.comments
+}
+
+func (parser *Parser) error(token *Token, format string, args ...any) {
+	parser.errors = append(parser.errors, &ParseError{
+		Token:   token,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (parser *Parser) warning(token *Token, format string, args ...any) {
+	parser.warnings = append(parser.warnings, &ParseError{
+		Token:   token,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (parser *Parser) expect(tokenType TokenType) bool {
+	if parser.current.Type != tokenType {
+		parser.error(
+			parser.current,
+			"expected %s, got %q",
+			GetTokenTypeString(tokenType),
+			parser.current.Literal,
+		)
+		return false
+	}
+
+	parser.advance()
+	return true
+}
+
+// enter guards the recursive parse functions, so deeply nested input is
+// reported instead of exhausting the stack. Every successful call must be
+// paired with a call to leave.
+func (parser *Parser) enter() bool {
+	if parser.depth >= MAX_NESTING_DEPTH {
+		parser.error(parser.current, "input is nested too deeply")
+		// give up on the rest of the input: recovering this deep down would
+		// report an error for every token left
+		parser.position = len(parser.tokens) - 1
+		parser.current = parser.tokens[parser.position]
+		return false
+	}
+
+	parser.depth += 1
+	return true
+}
+
+func (parser *Parser) leave() {
+	parser.depth -= 1
+}
+
 func (parser *Parser) parseLetStatement() AstStatement {
-	letStatement := &AstLetStatement{Token: parser.current}
+	letStatement := parser.arena.letStatements.alloc(AstLetStatement{Token: parser.current})
 	parser.advance()
 
-	identifier := parser.parseIdentifier()
-	letStatement.Identifier = identifier
+	switch parser.current.Type {
+	case TOKEN_OPEN_BRACKET, TOKEN_OPEN_BRACE:
+		pattern := parser.parsePattern()
+		if pattern == nil || !parser.checkDuplicateBindings(pattern) {
+			return nil
+		}
+		letStatement.Pattern = pattern
+	case TOKEN_IDENTIFIER:
+		identifier := parser.parseIdentifier()
+		letStatement.Identifier = identifier
+	default:
+		parser.error(
+			parser.current,
+			"expected a name or a pattern after \"let\", got %q",
+			parser.current.Literal,
+		)
+		return nil
+	}
 
-	if parser.current.Type != TOKEN_ASSIGNMENT {
-		// TODO: handle errors
+	if parser.current.Type == TOKEN_COLON {
+		parser.advance()
+		letStatement.Type = parser.parseType()
+		if letStatement.Type == nil {
+			return nil
+		}
+	}
+
+	if !parser.expect(TOKEN_ASSIGNMENT) {
 		return nil
 	}
 
-	parser.advance()
 	letValue := parser.parseExpression(PRECEDENCE_LOWEST)
+	if letValue == nil {
+		return nil
+	}
 	letStatement.Value = letValue
 
 	if parser.current.Type == TOKEN_SEMICOLON {
@@ -90,11 +255,18 @@ func (parser *Parser) parseLetStatement() AstStatement {
 }
 
 func (parser *Parser) parseReturnStatement() AstStatement {
-	returnStatement := &AstReturnStatement{Token: parser.current}
+	returnStatement := parser.arena.returnStatements.alloc(AstReturnStatement{Token: parser.current})
 
 	parser.advance()
-	returnValueExpression := parser.parseExpression(PRECEDENCE_LOWEST)
-	returnStatement.Value = returnValueExpression
+	if parser.current.Type != TOKEN_SEMICOLON &&
+		parser.current.Type != TOKEN_CLOSE_BRACE &&
+		parser.current.Type != TOKEN_EOF {
+		returnValueExpression := parser.parseExpression(PRECEDENCE_LOWEST)
+		if returnValueExpression == nil {
+			return nil
+		}
+		returnStatement.Value = returnValueExpression
+	}
 
 	if parser.current.Type == TOKEN_SEMICOLON {
 		parser.advance()
@@ -106,13 +278,18 @@ func (parser *Parser) parseReturnStatement() AstStatement {
 func (parser *Parser) parseIntegerLiteral() AstExpression {
 	value, err := strconv.ParseInt(parser.current.Literal, 10, 64)
 	if err != nil {
+		parser.error(
+			parser.current,
+			"could not parse %q as an integer",
+			parser.current.Literal,
+		)
 		return nil
 	}
 
-	integerLiteral := &AstIntegerLiteral{
+	integerLiteral := parser.arena.integerLiterals.alloc(AstIntegerLiteral{
 		Token: parser.current,
 		Value: value,
-	}
+	})
 
 	parser.advance()
 
@@ -120,18 +297,29 @@ func (parser *Parser) parseIntegerLiteral() AstExpression {
 }
 
 func (parser *Parser) parseBooleanLiteral() AstExpression {
-	booleanLiteral := &AstBooleanLiteral{
+	booleanLiteral := parser.arena.booleanLiterals.alloc(AstBooleanLiteral{
 		Token: parser.current,
 		Value: parser.current.Type == TOKEN_TRUE,
-	}
+	})
 
 	parser.advance()
 
 	return booleanLiteral
 }
 
+func (parser *Parser) parseStringLiteral() AstExpression {
+	stringLiteral := parser.arena.stringLiterals.alloc(AstStringLiteral{
+		Token: parser.current,
+		Value: parser.current.Literal,
+	})
+
+	parser.advance()
+
+	return stringLiteral
+}
+
 func (parser *Parser) parsePrefixExpression() AstExpression {
-	prefixExpression := &AstPrefixExpression{Token: parser.current}
+	prefixExpression := parser.arena.prefixExpressions.alloc(AstPrefixExpression{Token: parser.current})
 
 	switch parser.current.Type {
 	case TOKEN_MINUS:
@@ -143,108 +331,454 @@ func (parser *Parser) parsePrefixExpression() AstExpression {
 		parser.advance()
 		prefixExpression.Right = parser.parseExpression(PRECEDENCE_PREFIX)
 	default:
-		// TODO: handle errors
+		parser.error(
+			parser.current,
+			"unexpected prefix operator %q",
+			parser.current.Literal,
+		)
 		return nil
 	}
 
-	return prefixExpression
+	if prefixExpression.Right == nil {
+		return nil
+	}
 
+	return prefixExpression
 }
 
 func (parser *Parser) parseInfixExpression(left AstExpression) AstExpression {
-	infixExpression := &AstInfixExpression{
+	infixExpression := parser.arena.infixExpressions.alloc(AstInfixExpression{
 		Token:    parser.current,
 		Left:     left,
 		Operator: parser.current.Literal,
-	}
+	})
 
 	precedence := precedences[parser.current.Type]
+	if associativities[parser.current.Type] == ASSOCIATIVITY_RIGHT {
+		// let an operator of the same precedence claim the right operand
+		precedence -= 1
+	}
 	parser.advance()
 	infixExpression.Right = parser.parseExpression(precedence)
+	if infixExpression.Right == nil {
+		return nil
+	}
 
 	return infixExpression
 }
 
+func (parser *Parser) parsePipeExpression(left AstExpression) AstExpression {
+	pipeExpression := parser.arena.pipeExpressions.alloc(AstPipeExpression{
+		Token: parser.current,
+		Left:  left,
+	})
+
+	parser.advance()
+	pipeExpression.Right = parser.parseExpression(PRECEDENCE_PIPE)
+	if pipeExpression.Right == nil {
+		return nil
+	}
+
+	return pipeExpression
+}
+
+func (parser *Parser) parseRangeExpression(start AstExpression) AstExpression {
+	rangeExpression := parser.arena.rangeExpressions.alloc(AstRangeExpression{
+		Token:     parser.current,
+		Start:     start,
+		Inclusive: parser.current.Type == TOKEN_RANGE_INCLUSIVE,
+	})
+
+	parser.advance()
+	rangeExpression.Stop = parser.parseExpression(PRECEDENCE_RANGE)
+	if rangeExpression.Stop == nil {
+		return nil
+	}
+
+	return rangeExpression
+}
+
 func (parser *Parser) parseEnforcedPrecedenceExpression() AstExpression {
+	groupedExpression := parser.arena.groupedExpressions.alloc(AstGroupedExpression{Token: parser.current})
 	parser.advance()
+
 	expression := parser.parseExpression(PRECEDENCE_LOWEST)
-	parser.advance()
-	return expression
+	if expression == nil {
+		return nil
+	}
+
+	groupedExpression.Close = parser.current
+	if !parser.expect(TOKEN_CLOSE_PAREN) {
+		return nil
+	}
+
+	if parser.collapseGroups {
+		return expression
+	}
+
+	groupedExpression.Expression = expression
+
+	return groupedExpression
 }
 
 func (parser *Parser) parseIdentifier() *AstIdentifier {
-	identifier := &AstIdentifier{
+	identifier := parser.arena.identifiers.alloc(AstIdentifier{
 		Token: parser.current,
 		Value: parser.current.Literal,
-	}
+	})
 	parser.advance()
 	return identifier
 }
 
 func (parser *Parser) parseFunctionCall() AstExpression {
-	functionCall := &AstFunctionCall{Token: parser.current}
+	functionCall := parser.arena.functionCalls.alloc(AstFunctionCall{Token: parser.current})
 
 	identifier := parser.parseIdentifier()
 	functionCall.Identifier = identifier
 
-	// TODO: expect an open paren
-	parser.advance()
+	if !parser.expect(TOKEN_OPEN_PAREN) {
+		return nil
+	}
 
 	arguments := []AstExpression{}
 	for parser.current.Type != TOKEN_CLOSE_PAREN {
-		expression := parser.parseExpression(PRECEDENCE_LOWEST)
-		arguments = append(arguments, expression)
-		// TODO: expect a comma
-		if parser.current.Type == TOKEN_COMMA {
+		var expression AstExpression
+		if parser.current.Type == TOKEN_ELLIPSIS {
+			spread := parser.arena.spreadExpressions.alloc(AstSpreadExpression{Token: parser.current})
 			parser.advance()
+			spread.Value = parser.parseExpression(PRECEDENCE_LOWEST)
+			if spread.Value == nil {
+				return nil
+			}
+			expression = spread
+		} else {
+			expression = parser.parseExpression(PRECEDENCE_LOWEST)
+			if expression == nil {
+				return nil
+			}
+		}
+		arguments = append(arguments, expression)
+		if parser.current.Type != TOKEN_COMMA {
+			break
 		}
+		parser.advance()
 	}
 
-	// TODO: expect a close paren
-	parser.advance()
+	functionCall.Close = parser.current
+	if !parser.expect(TOKEN_CLOSE_PAREN) {
+		return nil
+	}
 
 	functionCall.Arguments = arguments
 
 	return functionCall
 }
 
+func (parser *Parser) parseType() AstType {
+	if !parser.enter() {
+		return nil
+	}
+	defer parser.leave()
+
+	switch parser.current.Type {
+	case TOKEN_IDENTIFIER:
+		namedType := parser.arena.namedTypes.alloc(AstNamedType{
+			Token: parser.current,
+			Name:  parser.current.Literal,
+		})
+		parser.advance()
+		return namedType
+	case TOKEN_OPEN_BRACKET:
+		arrayType := parser.arena.arrayTypes.alloc(AstArrayType{Token: parser.current})
+		parser.advance()
+		arrayType.Element = parser.parseType()
+		if arrayType.Element == nil {
+			return nil
+		}
+		arrayType.Close = parser.current
+		if !parser.expect(TOKEN_CLOSE_BRACKET) {
+			return nil
+		}
+		return arrayType
+	case TOKEN_FUNCTION:
+		functionType := parser.arena.functionTypes.alloc(AstFunctionType{Token: parser.current})
+		parser.advance()
+		if !parser.expect(TOKEN_OPEN_PAREN) {
+			return nil
+		}
+		params := []AstType{}
+		for parser.current.Type != TOKEN_CLOSE_PAREN {
+			param := parser.parseType()
+			if param == nil {
+				return nil
+			}
+			params = append(params, param)
+			if parser.current.Type != TOKEN_COMMA {
+				break
+			}
+			parser.advance()
+		}
+		functionType.Close = parser.current
+		if !parser.expect(TOKEN_CLOSE_PAREN) {
+			return nil
+		}
+		functionType.Params = params
+		if parser.current.Type == TOKEN_ARROW {
+			parser.advance()
+			functionType.Return = parser.parseType()
+			if functionType.Return == nil {
+				return nil
+			}
+		}
+		return functionType
+	default:
+		parser.error(
+			parser.current,
+			"expected a type, got %q",
+			parser.current.Literal,
+		)
+		return nil
+	}
+}
+
+func (parser *Parser) parseParameter() *AstParameter {
+	param := parser.arena.parameters.alloc(AstParameter{Token: parser.current})
+
+	if parser.current.Type == TOKEN_ELLIPSIS {
+		param.Rest = true
+		parser.advance()
+	}
+
+	if parser.current.Type != TOKEN_IDENTIFIER {
+		parser.error(
+			parser.current,
+			"expected a parameter name, got %q",
+			parser.current.Literal,
+		)
+		return nil
+	}
+	param.Identifier = parser.parseIdentifier()
+
+	if parser.current.Type == TOKEN_COLON {
+		parser.advance()
+		param.Type = parser.parseType()
+		if param.Type == nil {
+			return nil
+		}
+	}
+
+	if parser.current.Type == TOKEN_ASSIGNMENT {
+		if param.Rest {
+			parser.error(
+				parser.current,
+				"rest parameter %q cannot have a default value",
+				param.Identifier.Value,
+			)
+			return nil
+		}
+		parser.advance()
+		param.Default = parser.parseExpression(PRECEDENCE_LOWEST)
+		if param.Default == nil {
+			return nil
+		}
+	}
+
+	return param
+}
+
+func (parser *Parser) parseParameters() []*AstParameter {
+	params := []*AstParameter{}
+	hasDefault := false
+
+	for parser.current.Type != TOKEN_CLOSE_PAREN {
+		param := parser.parseParameter()
+		if param == nil {
+			return nil
+		}
+
+		if len(params) > 0 && params[len(params)-1].Rest {
+			parser.error(
+				param.Token,
+				"rest parameter %q must be the last parameter",
+				params[len(params)-1].Identifier.Value,
+			)
+			return nil
+		}
+		if param.Default != nil {
+			hasDefault = true
+		} else if hasDefault && !param.Rest {
+			parser.error(
+				param.Token,
+				"required parameter %q cannot follow a parameter with a default value",
+				param.Identifier.Value,
+			)
+			return nil
+		}
+
+		params = append(params, param)
+		if parser.current.Type != TOKEN_COMMA {
+			break
+		}
+		parser.advance()
+	}
+
+	return params
+}
+
 func (parser *Parser) parseFunctionDefinition() AstExpression {
-	functionDefinition := &AstFunctionDefinition{Token: parser.current}
+	functionDefinition := parser.arena.functionDefinitions.alloc(AstFunctionDefinition{Token: parser.current})
+	parser.advance()
+
+	if parser.parseFunctionParamsAndBody(functionDefinition) == nil {
+		return nil
+	}
+
+	return functionDefinition
+}
+
+func (parser *Parser) parseFunctionParamsAndBody(
+	functionDefinition *AstFunctionDefinition,
+) *AstFunctionDefinition {
+	if !parser.expect(TOKEN_OPEN_PAREN) {
+		return nil
+	}
+
+	params := parser.parseParameters()
+	if params == nil || !parser.expect(TOKEN_CLOSE_PAREN) {
+		return nil
+	}
+
+	functionDefinition.Params = params
+
+	if parser.current.Type == TOKEN_ARROW {
+		parser.advance()
+		functionDefinition.ReturnType = parser.parseType()
+		if functionDefinition.ReturnType == nil {
+			return nil
+		}
+	}
+
+	body := parser.parseBlock()
+	if body == nil {
+		return nil
+	}
+
+	functionDefinition.Body = body
 
-	// TODO: expect a function token
+	return functionDefinition
+}
+
+func (parser *Parser) parseFunctionDeclaration() AstStatement {
+	functionDeclaration := parser.arena.functionDeclarations.alloc(AstFunctionDeclaration{Token: parser.current})
+	functionDefinition := parser.arena.functionDefinitions.alloc(AstFunctionDefinition{Token: parser.current})
 	parser.advance()
-	// TODO: expect an open paren
+
+	functionDeclaration.Name = parser.parseIdentifier()
+
+	functionDeclaration.Function = parser.parseFunctionParamsAndBody(
+		functionDefinition,
+	)
+	if functionDeclaration.Function == nil {
+		return nil
+	}
+
+	if parser.current.Type == TOKEN_SEMICOLON {
+		parser.advance()
+	}
+
+	return functionDeclaration
+}
+
+func (parser *Parser) parseMacroLiteral() AstExpression {
+	macroLiteral := parser.arena.macroLiterals.alloc(AstMacroLiteral{Token: parser.current})
 	parser.advance()
 
+	if !parser.expect(TOKEN_OPEN_PAREN) {
+		return nil
+	}
+
 	params := []*AstIdentifier{}
 	for parser.current.Type != TOKEN_CLOSE_PAREN {
-		identifier := parser.parseIdentifier()
-		params = append(params, identifier)
-		// TODO: expect a comma
-		if parser.current.Type == TOKEN_COMMA {
-			parser.advance()
+		if parser.current.Type != TOKEN_IDENTIFIER {
+			parser.error(
+				parser.current,
+				"expected a macro parameter name, got %q",
+				parser.current.Literal,
+			)
+			return nil
 		}
+		params = append(params, parser.parseIdentifier())
+		if parser.current.Type != TOKEN_COMMA {
+			break
+		}
+		parser.advance()
 	}
 
-	// TODO: expect a close paren
-	parser.advance()
+	if !parser.expect(TOKEN_CLOSE_PAREN) {
+		return nil
+	}
 
-	functionDefinition.Params = params
+	macroLiteral.Params = params
+	macroLiteral.Body = parser.parseBlock()
+	if macroLiteral.Body == nil {
+		return nil
+	}
 
-	// TODO: expect an open brace
-	parser.advance()
+	return macroLiteral
+}
+
+func (parser *Parser) parseBlock() *AstCompound {
+	open := parser.current
+	if !parser.expect(TOKEN_OPEN_BRACE) {
+		return nil
+	}
+
+	block := parser.parseCompound()
+	block.Token = open
 
-	body := parser.parseCompound()
+	block.Close = parser.current
+	if !parser.expect(TOKEN_CLOSE_BRACE) {
+		return nil
+	}
+
+	return block
+}
 
-	// TODO: expect a close brace
+func (parser *Parser) parseIfExpression() AstExpression {
+	ifExpression := parser.arena.ifExpressions.alloc(AstIfExpression{Token: parser.current})
 	parser.advance()
 
-	functionDefinition.Body = body
+	if !parser.expect(TOKEN_OPEN_PAREN) {
+		return nil
+	}
 
-	return functionDefinition
+	ifExpression.Condition = parser.parseExpression(PRECEDENCE_LOWEST)
+	if ifExpression.Condition == nil || !parser.expect(TOKEN_CLOSE_PAREN) {
+		return nil
+	}
+
+	ifExpression.Consequence = parser.parseBlock()
+	if ifExpression.Consequence == nil {
+		return nil
+	}
+
+	if parser.current.Type == TOKEN_ELSE {
+		parser.advance()
+		ifExpression.Alternative = parser.parseBlock()
+		if ifExpression.Alternative == nil {
+			return nil
+		}
+	}
+
+	return ifExpression
 }
 
 func (parser *Parser) parseExpression(precedence int) AstExpression {
+	if !parser.enter() {
+		return nil
+	}
+	defer parser.leave()
+
 	var left AstExpression
 
 	switch parser.current.Type {
@@ -252,6 +786,8 @@ func (parser *Parser) parseExpression(precedence int) AstExpression {
 		left = parser.parseIntegerLiteral()
 	case TOKEN_TRUE, TOKEN_FALSE:
 		left = parser.parseBooleanLiteral()
+	case TOKEN_STRING:
+		left = parser.parseStringLiteral()
 	case TOKEN_MINUS, TOKEN_BANG:
 		left = parser.parsePrefixExpression()
 	case TOKEN_OPEN_PAREN:
@@ -264,24 +800,52 @@ func (parser *Parser) parseExpression(precedence int) AstExpression {
 		}
 	case TOKEN_FUNCTION:
 		left = parser.parseFunctionDefinition()
+	case TOKEN_MATCH:
+		left = parser.parseMatchExpression()
+	case TOKEN_MACRO:
+		left = parser.parseMacroLiteral()
+	case TOKEN_IF:
+		left = parser.parseIfExpression()
+	case TOKEN_ILLEGAL:
+		parser.error(
+			parser.current,
+			"illegal token %q",
+			parser.current.Literal,
+		)
+		return nil
 	default:
-		// TODO: handle errors
+		parser.error(
+			parser.current,
+			"unexpected %q, expected an expression",
+			parser.current.Literal,
+		)
 		return nil
 	}
 
-	for parser.current.Type != TOKEN_SEMICOLON &&
+	for left != nil &&
+		parser.current.Type != TOKEN_SEMICOLON &&
 		parser.current.Type != TOKEN_EOF &&
 		precedence < precedences[parser.current.Type] {
-		left = parser.parseInfixExpression(left)
+		switch parser.current.Type {
+		case TOKEN_PIPE:
+			left = parser.parsePipeExpression(left)
+		case TOKEN_RANGE, TOKEN_RANGE_INCLUSIVE:
+			left = parser.parseRangeExpression(left)
+		default:
+			left = parser.parseInfixExpression(left)
+		}
 	}
 
 	return left
 }
 
 func (parser *Parser) parseExpressionStatement() AstStatement {
-	expressionStatement := &AstExpressionStatement{Token: parser.current}
+	expressionStatement := parser.arena.expressionStatements.alloc(AstExpressionStatement{Token: parser.current})
 
 	expressionStatement.Expression = parser.parseExpression(PRECEDENCE_LOWEST)
+	if expressionStatement.Expression == nil {
+		return nil
+	}
 
 	if parser.current.Type == TOKEN_SEMICOLON {
 		parser.advance()
@@ -290,29 +854,162 @@ func (parser *Parser) parseExpressionStatement() AstStatement {
 	return expressionStatement
 }
 
+func (parser *Parser) parseImportStatement() AstStatement {
+	importStatement := parser.arena.importStatements.alloc(AstImportStatement{Token: parser.current})
+	parser.advance()
+
+	if parser.current.Type != TOKEN_STRING {
+		parser.error(
+			parser.current,
+			"expected an import path string, got %q",
+			parser.current.Literal,
+		)
+		return nil
+	}
+	importStatement.Path = parser.parseStringLiteral().(*AstStringLiteral)
+
+	if parser.current.Type == TOKEN_AS {
+		parser.advance()
+		if parser.current.Type != TOKEN_IDENTIFIER {
+			parser.error(
+				parser.current,
+				"expected an import alias, got %q",
+				parser.current.Literal,
+			)
+			return nil
+		}
+		importStatement.Alias = parser.parseIdentifier()
+	}
+
+	if parser.current.Type == TOKEN_SEMICOLON {
+		parser.advance()
+	}
+
+	return importStatement
+}
+
+func (parser *Parser) parseExportStatement() AstStatement {
+	exportStatement := parser.arena.exportStatements.alloc(AstExportStatement{Token: parser.current})
+	parser.advance()
+
+	switch {
+	case parser.current.Type == TOKEN_LET:
+		exportStatement.Statement = parser.parseLetStatement()
+	case parser.current.Type == TOKEN_FUNCTION &&
+		parser.peek().Type == TOKEN_IDENTIFIER:
+		exportStatement.Statement = parser.parseFunctionDeclaration()
+	default:
+		parser.error(
+			parser.current,
+			"only let statements and function declarations can be exported, got %q",
+			parser.current.Literal,
+		)
+		return nil
+	}
+
+	if exportStatement.Statement == nil {
+		return nil
+	}
+
+	return exportStatement
+}
+
+// parseStatement attaches the doc comment written right before a statement
+// to it. Doc comments left unattached are reported as dangling by Parse.
 func (parser *Parser) parseStatement() AstStatement {
+	if !parser.enter() {
+		return nil
+	}
+	defer parser.leave()
+
+	start := parser.position
+	statement := parser.parseUndocumentedStatement()
+	if doc, ok := parser.docs[start]; ok && attachDoc(statement, doc) {
+		delete(parser.docs, start)
+	}
+	return statement
+}
+
+func attachDoc(statement AstStatement, doc *AstDocComment) bool {
+	switch statement := statement.(type) {
+	case *AstLetStatement:
+		statement.Doc = doc
+	case *AstFunctionDeclaration:
+		statement.Doc = doc
+	case *AstExportStatement:
+		return attachDoc(statement.Statement, doc)
+	default:
+		return false
+	}
+	return true
+}
+
+func (parser *Parser) parseUndocumentedStatement() AstStatement {
 	switch parser.current.Type {
 	case TOKEN_LET:
 		return parser.parseLetStatement()
 	case TOKEN_RETURN:
 		return parser.parseReturnStatement()
+	case TOKEN_IMPORT:
+		return parser.parseImportStatement()
+	case TOKEN_EXPORT:
+		return parser.parseExportStatement()
+	case TOKEN_FUNCTION:
+		if parser.peek().Type == TOKEN_IDENTIFIER {
+			return parser.parseFunctionDeclaration()
+		}
+		return parser.parseExpressionStatement()
 	default:
 		return parser.parseExpressionStatement()
 	}
 }
 
 func (parser *Parser) parseCompound() *AstCompound {
-	compound := &AstCompound{Statements: []AstStatement{}}
+	compound := parser.arena.compounds.alloc(AstCompound{Statements: []AstStatement{}})
 
 	for parser.current.Type != TOKEN_EOF &&
 		parser.current.Type != TOKEN_CLOSE_BRACE {
+		if parser.current.Type == TOKEN_SEMICOLON {
+			// empty statement
+			parser.advance()
+			continue
+		}
+
+		position := parser.position
 		statement := parser.parseStatement()
-		compound.Statements = append(compound.Statements, statement)
+		if statement != nil {
+			compound.Statements = append(compound.Statements, statement)
+		}
+		if parser.position == position {
+			// no statement can start here, skip the token to keep going
+			parser.advance()
+		}
 	}
 
 	return compound
 }
 
 func (parser *Parser) Parse() *AstCompound {
-	return parser.parseCompound()
+	compound := parser.parseCompound()
+
+	for parser.current.Type != TOKEN_EOF {
+		parser.error(parser.current, "unexpected %q", parser.current.Literal)
+		parser.advance()
+		rest := parser.parseCompound()
+		compound.Statements = append(compound.Statements, rest.Statements...)
+	}
+
+	dangling := []int{}
+	for position := range parser.docs {
+		dangling = append(dangling, position)
+	}
+	sort.Ints(dangling)
+	for _, position := range dangling {
+		parser.warning(
+			parser.docs[position].Tokens[0],
+			"dangling doc comment, expected a let statement or a function declaration after it",
+		)
+	}
+
+	return compound
 }
@@ -0,0 +1,324 @@
+package monkey
+
+func (parser *Parser) parseLiteralPattern() AstPattern {
+	literalPattern := parser.arena.literalPatterns.alloc(AstLiteralPattern{Token: parser.current})
+
+	switch parser.current.Type {
+	case TOKEN_INTEGER:
+		literalPattern.Value = parser.parseIntegerLiteral()
+	case TOKEN_TRUE, TOKEN_FALSE:
+		literalPattern.Value = parser.parseBooleanLiteral()
+	case TOKEN_STRING:
+		literalPattern.Value = parser.parseStringLiteral()
+	case TOKEN_MINUS:
+		if parser.peek().Type != TOKEN_INTEGER {
+			parser.error(
+				parser.peek(),
+				"expected an integer after \"-\" in pattern, got %q",
+				parser.peek().Literal,
+			)
+			return nil
+		}
+		prefixExpression := parser.arena.prefixExpressions.alloc(AstPrefixExpression{
+			Token:    parser.current,
+			Operator: parser.current.Literal,
+		})
+		parser.advance()
+		prefixExpression.Right = parser.parseIntegerLiteral()
+		if prefixExpression.Right == nil {
+			return nil
+		}
+		literalPattern.Value = prefixExpression
+	}
+
+	if literalPattern.Value == nil {
+		return nil
+	}
+
+	return literalPattern
+}
+
+func (parser *Parser) parseRestPattern() AstPattern {
+	restPattern := parser.arena.restPatterns.alloc(AstRestPattern{Token: parser.current})
+	parser.advance()
+
+	if parser.current.Type != TOKEN_IDENTIFIER {
+		parser.error(
+			parser.current,
+			"expected an identifier after \"...\", got %q",
+			parser.current.Literal,
+		)
+		return nil
+	}
+	restPattern.Identifier = parser.parseIdentifier()
+
+	return restPattern
+}
+
+func (parser *Parser) parseArrayPattern() AstPattern {
+	arrayPattern := parser.arena.arrayPatterns.alloc(AstArrayPattern{Token: parser.current})
+	parser.advance()
+
+	elements := []AstPattern{}
+	for parser.current.Type != TOKEN_CLOSE_BRACKET {
+		if len(elements) > 0 {
+			if _, ok := elements[len(elements)-1].(*AstRestPattern); ok {
+				parser.error(
+					parser.current,
+					"rest element must be the last element of an array pattern",
+				)
+				return nil
+			}
+		}
+
+		var element AstPattern
+		if parser.current.Type == TOKEN_ELLIPSIS {
+			element = parser.parseRestPattern()
+		} else {
+			element = parser.parsePattern()
+		}
+		if element == nil {
+			return nil
+		}
+		elements = append(elements, element)
+		if parser.current.Type != TOKEN_COMMA {
+			break
+		}
+		parser.advance()
+	}
+
+	arrayPattern.Close = parser.current
+	if !parser.expect(TOKEN_CLOSE_BRACKET) {
+		return nil
+	}
+
+	arrayPattern.Elements = elements
+
+	return arrayPattern
+}
+
+func (parser *Parser) parseHashPatternPair() *AstHashPatternPair {
+	pair := parser.arena.hashPatternPairs.alloc(AstHashPatternPair{Token: parser.current})
+
+	switch parser.current.Type {
+	case TOKEN_STRING:
+		pair.Key = parser.parseStringLiteral()
+	case TOKEN_IDENTIFIER:
+		identifier := parser.parseIdentifier()
+		pair.Key = identifier
+		if parser.current.Type != TOKEN_COLON {
+			// the shorthand {name} binds a node of its own, so that the key
+			// and the binding are not the same node twice in the tree
+			binding := parser.arena.identifiers.alloc(*identifier)
+			pair.Value = parser.arena.identifierPatterns.alloc(AstIdentifierPattern{
+				Token:      binding.Token,
+				Identifier: binding,
+			})
+			return pair
+		}
+	default:
+		parser.error(
+			parser.current,
+			"expected a string or identifier key in hash pattern, got %q",
+			parser.current.Literal,
+		)
+		return nil
+	}
+
+	if !parser.expect(TOKEN_COLON) {
+		return nil
+	}
+
+	pair.Value = parser.parsePattern()
+	if pair.Value == nil {
+		return nil
+	}
+
+	return pair
+}
+
+func (parser *Parser) parseHashPattern() AstPattern {
+	hashPattern := parser.arena.hashPatterns.alloc(AstHashPattern{Token: parser.current})
+	parser.advance()
+
+	pairs := []*AstHashPatternPair{}
+	for parser.current.Type != TOKEN_CLOSE_BRACE {
+		pair := parser.parseHashPatternPair()
+		if pair == nil {
+			return nil
+		}
+		pairs = append(pairs, pair)
+		if parser.current.Type != TOKEN_COMMA {
+			break
+		}
+		parser.advance()
+	}
+
+	hashPattern.Close = parser.current
+	if !parser.expect(TOKEN_CLOSE_BRACE) {
+		return nil
+	}
+
+	hashPattern.Pairs = pairs
+
+	return hashPattern
+}
+
+func (parser *Parser) parsePattern() AstPattern {
+	if !parser.enter() {
+		return nil
+	}
+	defer parser.leave()
+
+	switch parser.current.Type {
+	case TOKEN_IDENTIFIER:
+		if parser.current.Literal == "_" {
+			wildcard := parser.arena.wildcardPatterns.alloc(AstWildcardPattern{Token: parser.current})
+			parser.advance()
+			return wildcard
+		}
+		identifier := parser.parseIdentifier()
+		return parser.arena.identifierPatterns.alloc(AstIdentifierPattern{
+			Token:      identifier.Token,
+			Identifier: identifier,
+		})
+	case TOKEN_INTEGER, TOKEN_TRUE, TOKEN_FALSE, TOKEN_STRING, TOKEN_MINUS:
+		return parser.parseLiteralPattern()
+	case TOKEN_OPEN_BRACKET:
+		return parser.parseArrayPattern()
+	case TOKEN_OPEN_BRACE:
+		return parser.parseHashPattern()
+	default:
+		parser.error(
+			parser.current,
+			"expected a pattern, got %q",
+			parser.current.Literal,
+		)
+		return nil
+	}
+}
+
+func collectPatternBindings(pattern AstPattern, bindings []*AstIdentifier) []*AstIdentifier {
+	switch pattern := pattern.(type) {
+	case *AstIdentifierPattern:
+		return append(bindings, pattern.Identifier)
+	case *AstRestPattern:
+		return append(bindings, pattern.Identifier)
+	case *AstArrayPattern:
+		for _, element := range pattern.Elements {
+			bindings = collectPatternBindings(element, bindings)
+		}
+	case *AstHashPattern:
+		for _, pair := range pattern.Pairs {
+			bindings = collectPatternBindings(pair.Value, bindings)
+		}
+	}
+	return bindings
+}
+
+func (parser *Parser) checkDuplicateBindings(pattern AstPattern) bool {
+	seen := map[string]bool{}
+	for _, identifier := range collectPatternBindings(pattern, nil) {
+		if seen[identifier.Value] {
+			parser.error(
+				identifier.Token,
+				"duplicate binding %q in pattern",
+				identifier.Value,
+			)
+			return false
+		}
+		seen[identifier.Value] = true
+	}
+	return true
+}
+
+func isIrrefutablePattern(pattern AstPattern) bool {
+	switch pattern.(type) {
+	case *AstWildcardPattern, *AstIdentifierPattern:
+		return true
+	default:
+		return false
+	}
+}
+
+func (parser *Parser) parseMatchArm() *AstMatchArm {
+	arm := parser.arena.matchArms.alloc(AstMatchArm{Token: parser.current})
+
+	arm.Pattern = parser.parsePattern()
+	if arm.Pattern == nil || !parser.checkDuplicateBindings(arm.Pattern) {
+		return nil
+	}
+
+	if parser.current.Type == TOKEN_IF {
+		parser.advance()
+		arm.Guard = parser.parseExpression(PRECEDENCE_LOWEST)
+		if arm.Guard == nil {
+			return nil
+		}
+	}
+
+	if !parser.expect(TOKEN_FAT_ARROW) {
+		return nil
+	}
+
+	arm.Body = parser.parseExpression(PRECEDENCE_LOWEST)
+	if arm.Body == nil {
+		return nil
+	}
+
+	return arm
+}
+
+func (parser *Parser) parseMatchExpression() AstExpression {
+	matchExpression := parser.arena.matchExpressions.alloc(AstMatchExpression{Token: parser.current})
+	parser.advance()
+
+	if !parser.expect(TOKEN_OPEN_PAREN) {
+		return nil
+	}
+
+	matchExpression.Subject = parser.parseExpression(PRECEDENCE_LOWEST)
+	if matchExpression.Subject == nil {
+		return nil
+	}
+
+	if !parser.expect(TOKEN_CLOSE_PAREN) || !parser.expect(TOKEN_OPEN_BRACE) {
+		return nil
+	}
+
+	arms := []*AstMatchArm{}
+	for parser.current.Type != TOKEN_CLOSE_BRACE {
+		arm := parser.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		arms = append(arms, arm)
+		if parser.current.Type != TOKEN_COMMA {
+			break
+		}
+		parser.advance()
+	}
+
+	matchExpression.Close = parser.current
+	if !parser.expect(TOKEN_CLOSE_BRACE) {
+		return nil
+	}
+
+	matchExpression.Arms = arms
+
+	exhaustive := false
+	for _, arm := range arms {
+		if arm.Guard == nil && isIrrefutablePattern(arm.Pattern) {
+			exhaustive = true
+			break
+		}
+	}
+	if !exhaustive {
+		parser.warning(
+			matchExpression.Token,
+			"match expression has no wildcard arm and may not be exhaustive",
+		)
+	}
+
+	return matchExpression
+}
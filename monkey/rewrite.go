@@ -0,0 +1,303 @@
+package monkey
+
+import "fmt"
+
+// Cursor describes the node being visited by Apply, and where it sits in its
+// parent. It is only valid during the call of the ApplyFunc it is given to.
+type Cursor struct {
+	parent AstNode
+	name   string
+	index  int
+	node   AstNode
+	set    func(AstNode)
+	// set for the statements of a compound, nil for any other node
+	statements *statementIterator
+}
+
+type statementIterator struct {
+	compound *AstCompound
+	index    int
+	step     int
+}
+
+func (cursor *Cursor) Node() AstNode {
+	return cursor.node
+}
+
+// Parent returns the node holding the current one, nil for the root.
+func (cursor *Cursor) Parent() AstNode {
+	return cursor.parent
+}
+
+// Name returns the name of the parent's field holding the current node, such
+// as "Value" or "Statements", and an empty string for the root.
+func (cursor *Cursor) Name() string {
+	return cursor.name
+}
+
+// Index returns the position of the current node in its parent's slice
+// field, or -1 when the field is not a slice.
+func (cursor *Cursor) Index() int {
+	if cursor.statements != nil {
+		return cursor.statements.index
+	}
+	return cursor.index
+}
+
+// Replace puts node where the current node is. It panics if node does not
+// fit in the parent's field, such as a statement given for an expression.
+func (cursor *Cursor) Replace(node AstNode) {
+	cursor.set(node)
+	cursor.node = node
+}
+
+func (cursor *Cursor) compound(method string) *statementIterator {
+	if cursor.statements == nil {
+		panic(fmt.Sprintf("%s: node is not in the statements of a compound", method))
+	}
+	return cursor.statements
+}
+
+// Delete removes the current statement from its compound. No other method
+// of the cursor may be called after it.
+func (cursor *Cursor) Delete() {
+	iterator := cursor.compound("Delete")
+	statements := iterator.compound.Statements
+	iterator.compound.Statements = append(statements[:iterator.index], statements[iterator.index+1:]...)
+	iterator.step -= 1
+}
+
+// InsertBefore inserts a statement before the current one. Apply does not
+// visit it.
+func (cursor *Cursor) InsertBefore(statement AstStatement) {
+	iterator := cursor.compound("InsertBefore")
+	statements := iterator.compound.Statements
+	statements = append(statements[:iterator.index], append([]AstStatement{statement}, statements[iterator.index:]...)...)
+	iterator.compound.Statements = statements
+	iterator.index += 1
+}
+
+// InsertAfter inserts a statement after the current one. Apply visits it
+// next.
+func (cursor *Cursor) InsertAfter(statement AstStatement) {
+	iterator := cursor.compound("InsertAfter")
+	statements := iterator.compound.Statements
+	statements = append(statements[:iterator.index+1], append([]AstStatement{statement}, statements[iterator.index+1:]...)...)
+	iterator.compound.Statements = statements
+}
+
+type ApplyFunc func(*Cursor) bool
+
+// Apply traverses a tree in the order of Walk, rewriting it in place. pre is
+// called before the children of a node, which are skipped when it returns
+// false; post is called after them, and returning false from it stops the
+// traversal. Either may be nil. Children of a node replaced by pre are the
+// children of the replacement. Apply returns the root, which may have been
+// replaced too.
+func Apply(root AstNode, pre ApplyFunc, post ApplyFunc) AstNode {
+	applier := &applier{pre: pre, post: post}
+	applier.apply(nil, "", root, func(node AstNode) { root = node })
+	return root
+}
+
+// Modify rewrites a tree bottom-up and in place, passing every node to fn
+// after its children and putting whatever fn returns in its place.
+func Modify(node AstNode, fn func(AstNode) AstNode) AstNode {
+	return Apply(node, nil, func(cursor *Cursor) bool {
+		cursor.Replace(fn(cursor.Node()))
+		return true
+	})
+}
+
+type applier struct {
+	pre     ApplyFunc
+	post    ApplyFunc
+	cursor  Cursor
+	stopped bool
+}
+
+func replacement[T AstNode](node AstNode) T {
+	if node == nil {
+		var empty T
+		return empty
+	}
+	return node.(T)
+}
+
+func (applier *applier) apply(parent AstNode, name string, node AstNode, set func(AstNode)) {
+	applier.applyAt(parent, name, -1, node, set)
+}
+
+func (applier *applier) applyAt(
+	parent AstNode,
+	name string,
+	index int,
+	node AstNode,
+	set func(AstNode),
+) {
+	applier.applyCursor(Cursor{parent: parent, name: name, index: index, node: node, set: set})
+}
+
+func (applier *applier) applyCursor(cursor Cursor) {
+	if applier.stopped {
+		return
+	}
+
+	saved := applier.cursor
+	defer func() { applier.cursor = saved }()
+	applier.cursor = cursor
+
+	if applier.pre != nil && !applier.pre(&applier.cursor) {
+		return
+	}
+
+	applier.children(applier.cursor.node)
+
+	if applier.post != nil && !applier.post(&applier.cursor) {
+		applier.stopped = true
+	}
+}
+
+func (applier *applier) applyStatements(compound *AstCompound) {
+	iterator := &statementIterator{compound: compound}
+	for iterator.index = 0; iterator.index < len(compound.Statements); iterator.index += iterator.step {
+		iterator.step = 1
+		index := iterator.index
+		applier.applyCursor(Cursor{
+			parent: compound,
+			name:   "Statements",
+			node:   compound.Statements[index],
+			set: func(node AstNode) {
+				compound.Statements[iterator.index] = replacement[AstStatement](node)
+			},
+			statements: iterator,
+		})
+	}
+}
+
+func (applier *applier) children(node AstNode) {
+	switch node := node.(type) {
+	case *AstCompound:
+		applier.applyStatements(node)
+	case *AstLetStatement:
+		if node.Doc != nil {
+			applier.apply(node, "Doc", node.Doc, func(n AstNode) { node.Doc = replacement[*AstDocComment](n) })
+		}
+		if node.Pattern != nil {
+			applier.apply(node, "Pattern", node.Pattern, func(n AstNode) { node.Pattern = replacement[AstPattern](n) })
+		} else if node.Identifier != nil {
+			applier.apply(node, "Identifier", node.Identifier, func(n AstNode) { node.Identifier = replacement[*AstIdentifier](n) })
+		}
+		if node.Type != nil {
+			applier.apply(node, "Type", node.Type, func(n AstNode) { node.Type = replacement[AstType](n) })
+		}
+		if node.Value != nil {
+			applier.apply(node, "Value", node.Value, func(n AstNode) { node.Value = replacement[AstExpression](n) })
+		}
+	case *AstReturnStatement:
+		if node.Value != nil {
+			applier.apply(node, "Value", node.Value, func(n AstNode) { node.Value = replacement[AstExpression](n) })
+		}
+	case *AstImportStatement:
+		applier.apply(node, "Path", node.Path, func(n AstNode) { node.Path = replacement[*AstStringLiteral](n) })
+		if node.Alias != nil {
+			applier.apply(node, "Alias", node.Alias, func(n AstNode) { node.Alias = replacement[*AstIdentifier](n) })
+		}
+	case *AstExportStatement:
+		applier.apply(node, "Statement", node.Statement, func(n AstNode) { node.Statement = replacement[AstStatement](n) })
+	case *AstExpressionStatement:
+		if node.Expression != nil {
+			applier.apply(node, "Expression", node.Expression, func(n AstNode) { node.Expression = replacement[AstExpression](n) })
+		}
+	case *AstFunctionDeclaration:
+		if node.Doc != nil {
+			applier.apply(node, "Doc", node.Doc, func(n AstNode) { node.Doc = replacement[*AstDocComment](n) })
+		}
+		applier.apply(node, "Name", node.Name, func(n AstNode) { node.Name = replacement[*AstIdentifier](n) })
+		applier.apply(node, "Function", node.Function, func(n AstNode) { node.Function = replacement[*AstFunctionDefinition](n) })
+	case *AstPrefixExpression:
+		applier.apply(node, "Right", node.Right, func(n AstNode) { node.Right = replacement[AstExpression](n) })
+	case *AstInfixExpression:
+		applier.apply(node, "Left", node.Left, func(n AstNode) { node.Left = replacement[AstExpression](n) })
+		applier.apply(node, "Right", node.Right, func(n AstNode) { node.Right = replacement[AstExpression](n) })
+	case *AstGroupedExpression:
+		applier.apply(node, "Expression", node.Expression, func(n AstNode) { node.Expression = replacement[AstExpression](n) })
+	case *AstFunctionCall:
+		applier.apply(node, "Identifier", node.Identifier, func(n AstNode) { node.Identifier = replacement[*AstIdentifier](n) })
+		for index := range node.Arguments {
+			applier.applyAt(node, "Arguments", index, node.Arguments[index], func(n AstNode) { node.Arguments[index] = replacement[AstExpression](n) })
+		}
+	case *AstParameter:
+		applier.apply(node, "Identifier", node.Identifier, func(n AstNode) { node.Identifier = replacement[*AstIdentifier](n) })
+		if node.Type != nil {
+			applier.apply(node, "Type", node.Type, func(n AstNode) { node.Type = replacement[AstType](n) })
+		}
+		if node.Default != nil {
+			applier.apply(node, "Default", node.Default, func(n AstNode) { node.Default = replacement[AstExpression](n) })
+		}
+	case *AstFunctionDefinition:
+		for index := range node.Params {
+			applier.applyAt(node, "Params", index, node.Params[index], func(n AstNode) { node.Params[index] = replacement[*AstParameter](n) })
+		}
+		if node.ReturnType != nil {
+			applier.apply(node, "ReturnType", node.ReturnType, func(n AstNode) { node.ReturnType = replacement[AstType](n) })
+		}
+		applier.apply(node, "Body", node.Body, func(n AstNode) { node.Body = replacement[*AstCompound](n) })
+	case *AstMacroLiteral:
+		for index := range node.Params {
+			applier.applyAt(node, "Params", index, node.Params[index], func(n AstNode) { node.Params[index] = replacement[*AstIdentifier](n) })
+		}
+		applier.apply(node, "Body", node.Body, func(n AstNode) { node.Body = replacement[*AstCompound](n) })
+	case *AstIfExpression:
+		applier.apply(node, "Condition", node.Condition, func(n AstNode) { node.Condition = replacement[AstExpression](n) })
+		applier.apply(node, "Consequence", node.Consequence, func(n AstNode) { node.Consequence = replacement[*AstCompound](n) })
+		if node.Alternative != nil {
+			applier.apply(node, "Alternative", node.Alternative, func(n AstNode) { node.Alternative = replacement[*AstCompound](n) })
+		}
+	case *AstSpreadExpression:
+		applier.apply(node, "Value", node.Value, func(n AstNode) { node.Value = replacement[AstExpression](n) })
+	case *AstPipeExpression:
+		applier.apply(node, "Left", node.Left, func(n AstNode) { node.Left = replacement[AstExpression](n) })
+		applier.apply(node, "Right", node.Right, func(n AstNode) { node.Right = replacement[AstExpression](n) })
+	case *AstRangeExpression:
+		applier.apply(node, "Start", node.Start, func(n AstNode) { node.Start = replacement[AstExpression](n) })
+		applier.apply(node, "Stop", node.Stop, func(n AstNode) { node.Stop = replacement[AstExpression](n) })
+	case *AstMatchExpression:
+		applier.apply(node, "Subject", node.Subject, func(n AstNode) { node.Subject = replacement[AstExpression](n) })
+		for index := range node.Arms {
+			applier.applyAt(node, "Arms", index, node.Arms[index], func(n AstNode) { node.Arms[index] = replacement[*AstMatchArm](n) })
+		}
+	case *AstMatchArm:
+		applier.apply(node, "Pattern", node.Pattern, func(n AstNode) { node.Pattern = replacement[AstPattern](n) })
+		if node.Guard != nil {
+			applier.apply(node, "Guard", node.Guard, func(n AstNode) { node.Guard = replacement[AstExpression](n) })
+		}
+		applier.apply(node, "Body", node.Body, func(n AstNode) { node.Body = replacement[AstExpression](n) })
+	case *AstIdentifierPattern:
+		applier.apply(node, "Identifier", node.Identifier, func(n AstNode) { node.Identifier = replacement[*AstIdentifier](n) })
+	case *AstLiteralPattern:
+		applier.apply(node, "Value", node.Value, func(n AstNode) { node.Value = replacement[AstExpression](n) })
+	case *AstArrayPattern:
+		for index := range node.Elements {
+			applier.applyAt(node, "Elements", index, node.Elements[index], func(n AstNode) { node.Elements[index] = replacement[AstPattern](n) })
+		}
+	case *AstRestPattern:
+		applier.apply(node, "Identifier", node.Identifier, func(n AstNode) { node.Identifier = replacement[*AstIdentifier](n) })
+	case *AstHashPattern:
+		for index := range node.Pairs {
+			applier.applyAt(node, "Pairs", index, node.Pairs[index], func(n AstNode) { node.Pairs[index] = replacement[*AstHashPatternPair](n) })
+		}
+	case *AstHashPatternPair:
+		applier.apply(node, "Key", node.Key, func(n AstNode) { node.Key = replacement[AstExpression](n) })
+		applier.apply(node, "Value", node.Value, func(n AstNode) { node.Value = replacement[AstPattern](n) })
+	case *AstArrayType:
+		applier.apply(node, "Element", node.Element, func(n AstNode) { node.Element = replacement[AstType](n) })
+	case *AstFunctionType:
+		for index := range node.Params {
+			applier.applyAt(node, "Params", index, node.Params[index], func(n AstNode) { node.Params[index] = replacement[AstType](n) })
+		}
+		if node.Return != nil {
+			applier.apply(node, "Return", node.Return, func(n AstNode) { node.Return = replacement[AstType](n) })
+		}
+	}
+}
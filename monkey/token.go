@@ -28,44 +28,87 @@ const (
 	TOKEN_IF
 	TOKEN_ELSE
 	TOKEN_RETURN
+	TOKEN_PIPE
+	TOKEN_STRING
+	TOKEN_MATCH
+	TOKEN_FAT_ARROW
+	TOKEN_OPEN_BRACKET
+	TOKEN_CLOSE_BRACKET
+	TOKEN_COLON
+	TOKEN_ELLIPSIS
+	TOKEN_RANGE
+	TOKEN_RANGE_INCLUSIVE
+	TOKEN_IN
+	TOKEN_MACRO
+	TOKEN_IMPORT
+	TOKEN_EXPORT
+	TOKEN_AS
+	TOKEN_POWER
+	TOKEN_ARROW
+	TOKEN_DOC_COMMENT
 )
 
 type TokenType int
 
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // starting at 1
+	Column int // byte column, starting at 1
+}
+
 type Token struct {
-	Type    TokenType
-	Literal string
+	Type     TokenType
+	Literal  string
+	Position Position // where the token starts
 }
 
 func GetTokenTypeString(tokenType TokenType) string {
 	types := map[TokenType]string{
-		TOKEN_ILLEGAL:      "Illegal",
-		TOKEN_EOF:          "Eof",
-		TOKEN_IDENTIFIER:   "Identifier",
-		TOKEN_ASSIGNMENT:   "Assignment",
-		TOKEN_PLUS:         "Plus",
-		TOKEN_MINUS:        "Minus",
-		TOKEN_BANG:         "Bang",
-		TOKEN_ASTERISK:     "Asterisk",
-		TOKEN_SLASH:        "Slash",
-		TOKEN_LESS_THAN:    "Less Than",
-		TOKEN_GREATER_THAN: "Greater Than",
-		TOKEN_EQUALS:       "Equals",
-		TOKEN_NOT_EQUALS:   "Not Equals",
-		TOKEN_COMMA:        "Comma",
-		TOKEN_SEMICOLON:    "Semicolon",
-		TOKEN_OPEN_PAREN:   "Open Paren",
-		TOKEN_CLOSE_PAREN:  "Close Paren",
-		TOKEN_OPEN_BRACE:   "Open Brace",
-		TOKEN_CLOSE_BRACE:  "Close Brace",
-		TOKEN_INTEGER:      "Integer",
-		TOKEN_FUNCTION:     "Function",
-		TOKEN_LET:          "Let",
-		TOKEN_TRUE:         "True",
-		TOKEN_FALSE:        "False",
-		TOKEN_IF:           "If",
-		TOKEN_ELSE:         "Else",
-		TOKEN_RETURN:       "Return",
+		TOKEN_ILLEGAL:         "Illegal",
+		TOKEN_EOF:             "Eof",
+		TOKEN_IDENTIFIER:      "Identifier",
+		TOKEN_ASSIGNMENT:      "Assignment",
+		TOKEN_PLUS:            "Plus",
+		TOKEN_MINUS:           "Minus",
+		TOKEN_BANG:            "Bang",
+		TOKEN_ASTERISK:        "Asterisk",
+		TOKEN_SLASH:           "Slash",
+		TOKEN_LESS_THAN:       "Less Than",
+		TOKEN_GREATER_THAN:    "Greater Than",
+		TOKEN_EQUALS:          "Equals",
+		TOKEN_NOT_EQUALS:      "Not Equals",
+		TOKEN_COMMA:           "Comma",
+		TOKEN_SEMICOLON:       "Semicolon",
+		TOKEN_OPEN_PAREN:      "Open Paren",
+		TOKEN_CLOSE_PAREN:     "Close Paren",
+		TOKEN_OPEN_BRACE:      "Open Brace",
+		TOKEN_CLOSE_BRACE:     "Close Brace",
+		TOKEN_INTEGER:         "Integer",
+		TOKEN_FUNCTION:        "Function",
+		TOKEN_LET:             "Let",
+		TOKEN_TRUE:            "True",
+		TOKEN_FALSE:           "False",
+		TOKEN_IF:              "If",
+		TOKEN_ELSE:            "Else",
+		TOKEN_RETURN:          "Return",
+		TOKEN_PIPE:            "Pipe",
+		TOKEN_STRING:          "String",
+		TOKEN_MATCH:           "Match",
+		TOKEN_FAT_ARROW:       "Fat Arrow",
+		TOKEN_OPEN_BRACKET:    "Open Bracket",
+		TOKEN_CLOSE_BRACKET:   "Close Bracket",
+		TOKEN_COLON:           "Colon",
+		TOKEN_ELLIPSIS:        "Ellipsis",
+		TOKEN_RANGE:           "Range",
+		TOKEN_RANGE_INCLUSIVE: "Range Inclusive",
+		TOKEN_IN:              "In",
+		TOKEN_MACRO:           "Macro",
+		TOKEN_IMPORT:          "Import",
+		TOKEN_EXPORT:          "Export",
+		TOKEN_AS:              "As",
+		TOKEN_POWER:           "Power",
+		TOKEN_ARROW:           "Arrow",
+		TOKEN_DOC_COMMENT:     "Doc Comment",
 	}
 	return types[tokenType]
 }
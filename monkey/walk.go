@@ -0,0 +1,183 @@
+package monkey
+
+import "fmt"
+
+// A Visitor's Visit method is called for every node found by Walk. If the
+// returned visitor is not nil, Walk visits each child of the node with it,
+// followed by a call of Visit(nil).
+type Visitor interface {
+	Visit(node AstNode) Visitor
+}
+
+// Walk traverses an AST in depth-first order, children in the order they
+// appear in the source:
+//
+//   - let: the doc comment, the identifier or the pattern, the type, the value
+//   - function declaration: the doc comment, the name, the function
+//   - function definition: the parameters, the return type, the body
+//   - parameter: the identifier, the type, the default value
+//   - call: the identifier, then the arguments
+//   - match: the subject, then each arm's pattern, guard and body
+//
+// Every other node visits its fields in declaration order. Optional children
+// that are missing are skipped.
+func Walk(visitor Visitor, node AstNode) {
+	if visitor = visitor.Visit(node); visitor == nil {
+		return
+	}
+
+	switch node := node.(type) {
+	case *AstCompound:
+		for _, statement := range node.Statements {
+			Walk(visitor, statement)
+		}
+	case *AstLetStatement:
+		if node.Doc != nil {
+			Walk(visitor, node.Doc)
+		}
+		if node.Pattern != nil {
+			Walk(visitor, node.Pattern)
+		} else if node.Identifier != nil {
+			Walk(visitor, node.Identifier)
+		}
+		if node.Type != nil {
+			Walk(visitor, node.Type)
+		}
+		if node.Value != nil {
+			Walk(visitor, node.Value)
+		}
+	case *AstReturnStatement:
+		if node.Value != nil {
+			Walk(visitor, node.Value)
+		}
+	case *AstImportStatement:
+		Walk(visitor, node.Path)
+		if node.Alias != nil {
+			Walk(visitor, node.Alias)
+		}
+	case *AstExportStatement:
+		Walk(visitor, node.Statement)
+	case *AstExpressionStatement:
+		if node.Expression != nil {
+			Walk(visitor, node.Expression)
+		}
+	case *AstFunctionDeclaration:
+		if node.Doc != nil {
+			Walk(visitor, node.Doc)
+		}
+		Walk(visitor, node.Name)
+		Walk(visitor, node.Function)
+	case *AstPrefixExpression:
+		Walk(visitor, node.Right)
+	case *AstInfixExpression:
+		Walk(visitor, node.Left)
+		Walk(visitor, node.Right)
+	case *AstGroupedExpression:
+		Walk(visitor, node.Expression)
+	case *AstFunctionCall:
+		Walk(visitor, node.Identifier)
+		for _, argument := range node.Arguments {
+			Walk(visitor, argument)
+		}
+	case *AstParameter:
+		Walk(visitor, node.Identifier)
+		if node.Type != nil {
+			Walk(visitor, node.Type)
+		}
+		if node.Default != nil {
+			Walk(visitor, node.Default)
+		}
+	case *AstFunctionDefinition:
+		for _, param := range node.Params {
+			Walk(visitor, param)
+		}
+		if node.ReturnType != nil {
+			Walk(visitor, node.ReturnType)
+		}
+		Walk(visitor, node.Body)
+	case *AstMacroLiteral:
+		for _, param := range node.Params {
+			Walk(visitor, param)
+		}
+		Walk(visitor, node.Body)
+	case *AstIfExpression:
+		Walk(visitor, node.Condition)
+		Walk(visitor, node.Consequence)
+		if node.Alternative != nil {
+			Walk(visitor, node.Alternative)
+		}
+	case *AstSpreadExpression:
+		Walk(visitor, node.Value)
+	case *AstPipeExpression:
+		Walk(visitor, node.Left)
+		Walk(visitor, node.Right)
+	case *AstRangeExpression:
+		Walk(visitor, node.Start)
+		Walk(visitor, node.Stop)
+	case *AstMatchExpression:
+		Walk(visitor, node.Subject)
+		for _, arm := range node.Arms {
+			Walk(visitor, arm)
+		}
+	case *AstMatchArm:
+		Walk(visitor, node.Pattern)
+		if node.Guard != nil {
+			Walk(visitor, node.Guard)
+		}
+		Walk(visitor, node.Body)
+	case *AstIdentifierPattern:
+		Walk(visitor, node.Identifier)
+	case *AstLiteralPattern:
+		Walk(visitor, node.Value)
+	case *AstArrayPattern:
+		for _, element := range node.Elements {
+			Walk(visitor, element)
+		}
+	case *AstRestPattern:
+		Walk(visitor, node.Identifier)
+	case *AstHashPattern:
+		for _, pair := range node.Pairs {
+			Walk(visitor, pair)
+		}
+	case *AstHashPatternPair:
+		Walk(visitor, node.Key)
+		Walk(visitor, node.Value)
+	case *AstArrayType:
+		Walk(visitor, node.Element)
+	case *AstFunctionType:
+		for _, param := range node.Params {
+			Walk(visitor, param)
+		}
+		if node.Return != nil {
+			Walk(visitor, node.Return)
+		}
+	case *AstIdentifier,
+		*AstIntegerLiteral,
+		*AstBooleanLiteral,
+		*AstStringLiteral,
+		*AstWildcardPattern,
+		*AstNamedType,
+		*AstDocComment:
+		// leaves
+	default:
+		panic(fmt.Sprintf("Walk: unexpected node type %T", node))
+	}
+
+	visitor.Visit(nil)
+}
+
+type inspector func(AstNode) bool
+
+func (fn inspector) Visit(node AstNode) Visitor {
+	if fn(node) {
+		return fn
+	}
+	return nil
+}
+
+// Inspect traverses an AST in the order of Walk, calling fn for every node.
+// The children of a node are skipped when fn returns false for it. After the
+// children of a node, fn is called with nil.
+func Inspect(node AstNode, fn func(AstNode) bool) {
+	Walk(inspector(fn), node)
+}
@@ -0,0 +1,94 @@
+package test
+
+import (
+	"fmt"
+	"monkey/monkey"
+	"strings"
+	"testing"
+)
+
+// syntheticCorpus generates a program of count functions using most of the
+// syntax, like the generated files the arena is meant for.
+func syntheticCorpus(count int) string {
+	var out strings.Builder
+
+	out.WriteString("import \"lib/math\" as math\n\n")
+	for index := 0; index < count; index++ {
+		fmt.Fprintf(&out, `/// Computes the value number %[1]d.
+export fn compute%[1]d(a: int, b: int = %[1]d, ...rest) -> int {
+  let [first, ...others] = rest
+  let {name, size: total} = options(a, b)
+  let scaled = (a + b) * %[1]d - -first ** 2
+  let kind = match (scaled) {
+    0 => "zero",
+    [x, y] if x > y => x,
+    {"kind": k} => k,
+    _ => "other",
+  }
+  if (a < b) { return 0..=b |> map(fn(x) { x * 2 }) } else { total }
+}
+`, index)
+	}
+
+	return out.String()
+}
+
+func TestArenaParser(t *testing.T) {
+	inputs := append([]string{syntheticCorpus(20)}, fuzzSeeds...)
+	arena := monkey.NewArena()
+
+	for _, input := range inputs {
+		parser := monkey.NewParser(monkey.NewLexer(input))
+		program := parser.Parse()
+
+		arenaParser := monkey.NewArenaParser(monkey.NewLexer(input), arena)
+		arenaProgram := arenaParser.Parse()
+
+		if difference := monkey.Diff(program, arenaProgram, monkey.EqualOptions{}); difference != nil {
+			t.Errorf("Expected the same tree from the arena parser for %q:\n%s", input, difference)
+		}
+		if len(parser.Errors()) != len(arenaParser.Errors()) {
+			t.Errorf(
+				"Expected %d errors from the arena parser for %q, got %d",
+				len(parser.Errors()),
+				input,
+				len(arenaParser.Errors()),
+			)
+		}
+	}
+}
+
+func TestArenaParserAllocations(t *testing.T) {
+	input := syntheticCorpus(50)
+
+	heap := testing.AllocsPerRun(5, func() {
+		monkey.NewParser(monkey.NewLexer(input)).Parse()
+	})
+	arena := testing.AllocsPerRun(5, func() {
+		monkey.NewArenaParser(monkey.NewLexer(input), monkey.NewArena()).Parse()
+	})
+
+	if arena > heap/2 {
+		t.Errorf("Expected the arena parser to allocate less than half of %.0f, got %.0f", heap, arena)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	input := syntheticCorpus(1000)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+
+	for index := 0; index < b.N; index++ {
+		monkey.NewParser(monkey.NewLexer(input)).Parse()
+	}
+}
+
+func BenchmarkParseArena(b *testing.B) {
+	input := syntheticCorpus(1000)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+
+	for index := 0; index < b.N; index++ {
+		monkey.NewArenaParser(monkey.NewLexer(input), monkey.NewArena()).Parse()
+	}
+}
@@ -0,0 +1,121 @@
+package test
+
+import "testing"
+
+func TestCheck(t *testing.T) {
+	expectations := []struct {
+		input   string
+		message string
+		line    int
+		column  int
+	}{
+		{`let x: int = "five";`, `cannot assign string to "x" of type int`, 1, 5},
+		{`let x: string = 1 + 2;`, `cannot assign int to "x" of type string`, 1, 5},
+		{`let x: bool = 1 < 2; let y: int = x;`, `cannot assign bool to "y" of type int`, 1, 26},
+		{`let xs: [string] = 0..10;`, `cannot assign [int] to "xs" of type [string]`, 1, 5},
+		{`let x: float = 1;`, `unknown type "float"`, 1, 8},
+		{`let x: int = 1 + true;`, `operator + expects int, got bool`, 1, 16},
+		{`let s = "a" + 1;`, `operator + expects string, got int`, 1, 13},
+		{`let x = -"a";`, `operator - expects int, got string`, 1, 9},
+		{
+			"fn f(a: int) -> int {\n  return true;\n}",
+			`cannot return bool from a function returning int`,
+			2, 3,
+		},
+		{
+			"fn f(a: int) -> string {\n  a\n}",
+			`cannot return int from a function returning string`,
+			2, 3,
+		},
+		{
+			"fn f(a: int, b: string) { a }\nf(1, 2)",
+			`cannot use int as argument 2 of "f", expected string`,
+			2, 1,
+		},
+		{
+			"let x = f(true);\nfn f(a: int) { a }",
+			`cannot use bool as argument 1 of "f", expected int`,
+			1, 9,
+		},
+		{
+			"let f = fn (a: string) { a };\n\"a\" |> f |> g\n1 |> f",
+			`cannot use int as argument 1 of "f", expected string`,
+			3, 6,
+		},
+		{
+			"fn f(a: int = \"one\") { a }",
+			`cannot use string as the default of "a" of type int`,
+			1, 6,
+		},
+		{
+			"fn f() -> int { 1 }\nlet g: fn(int) -> int = f;",
+			`cannot assign fn() -> int to "g" of type fn(int) -> int`,
+			2, 5,
+		},
+		{
+			"fn f(a: int) -> bool { a == 1 }\nlet x: int = if (c) { f(1) } else { 1 };\nlet y: int = if (c) { f(1) } else { false };",
+			`cannot assign bool to "y" of type int`,
+			3, 5,
+		},
+		{
+			"fn f(a: int, ...xs: [int]) { a }\nf(1, 2, \"three\")",
+			`cannot use string as argument 3 of "f", expected int`,
+			2, 1,
+		},
+		{
+			"fn f(a: int, b: int) { a }\nf(1)",
+			`not enough arguments to "f", expected 2, got 1`,
+			2, 1,
+		},
+		{
+			"fn f(a, b = 1, ...rest) { a }\nf()",
+			`not enough arguments to "f", expected at least 1, got 0`,
+			2, 1,
+		},
+		{
+			"fn f(a: int, b: int) { a }\nf(1, 2, 3)",
+			`too many arguments to "f", expected at most 2, got 3`,
+			2, 1,
+		},
+	}
+
+	for _, expectation := range expectations {
+		errors := checkProgram(t, expectation.input)
+
+		if len(errors) == 0 {
+			t.Fatalf("Expected check errors for %q, got none.", expectation.input)
+		}
+
+		expectErrorAt(t, errors[0], errors[0].Token, expectation.message, expectation.line, expectation.column)
+	}
+}
+
+func TestCheckAcceptsDynamicCode(t *testing.T) {
+	inputs := []string{
+		`let x = 5; let y = x + "a";`,
+		`let x: int = y;`,
+		`let x: any = "a"; let y: int = x;`,
+		`let f = fn (a) { a }; let x: string = f(1);`,
+		`fn f(a: int, b) -> int { a + b } let x: int = f(1, "b");`,
+		`fn f(...xs: [int]) -> int { 0 } let x: int = f(...ys);`,
+		`fn f(...xs: [int]) -> int { 0 } let x: int = f(1, 2);`,
+		`fn f(a: int, ...xs: [int]) -> int { a } let x: int = f(1, 2, 3);`,
+		`fn f(a: int, b = 1) -> int { a } let x: int = f(1) + f(1, 2);`,
+		`fn f(a, b) { a } let x = f(...ys);`,
+		`let f: fn(int) -> any = fn (a: int) -> string { "a" };`,
+		`let x: [int] = 1..=3; let b: bool = 1 in x;`,
+		`let [a, b]: [int] = 0..2; let c: string = a;`,
+		`let x: int = match (y) { 1 => "one", _ => 2 };`,
+		`let x: int = if (c) { 1 } else { "a" };`,
+		"fn f(a: int) -> int {\n  if (a < 0) { return 0; }\n  a * 2\n}",
+		`let s: string = "a" + "b"; let b: bool = s == "ab";`,
+	}
+
+	for _, input := range inputs {
+		errors := checkProgram(t, input)
+
+		if len(errors) > 0 {
+			t.Fatalf("Unexpected check error for %q: %s", input, errors[0])
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	"monkey/monkey"
+	"reflect"
+	"testing"
+)
+
+// collectPointers gathers every pointer reachable from value.
+func collectPointers(value reflect.Value, pointers map[uintptr]bool) {
+	switch value.Kind() {
+	case reflect.Interface:
+		if !value.IsNil() {
+			collectPointers(value.Elem(), pointers)
+		}
+	case reflect.Pointer:
+		if value.IsNil() || pointers[value.Pointer()] {
+			return
+		}
+		pointers[value.Pointer()] = true
+		collectPointers(value.Elem(), pointers)
+	case reflect.Struct:
+		for index := 0; index < value.NumField(); index++ {
+			collectPointers(value.Field(index), pointers)
+		}
+	case reflect.Slice:
+		if value.Len() > 0 {
+			pointers[value.Pointer()] = true
+		}
+		for index := 0; index < value.Len(); index++ {
+			collectPointers(value.Index(index), pointers)
+		}
+	}
+}
+
+func TestCloneSharesNothing(t *testing.T) {
+	program := parseProgram(t, syntaxSample)
+	cloned := monkey.Clone(program)
+
+	if difference := monkey.Diff(program, cloned, monkey.EqualOptions{}); difference != nil {
+		t.Fatalf("Expected the clone to equal the original:\n%s", difference)
+	}
+
+	original := map[uintptr]bool{}
+	collectPointers(reflect.ValueOf(program), original)
+	copied := map[uintptr]bool{}
+	collectPointers(reflect.ValueOf(cloned), copied)
+
+	if len(original) != len(copied) {
+		t.Fatalf("Expected %d pointers in the clone, got %d.", len(original), len(copied))
+	}
+
+	for pointer := range copied {
+		if original[pointer] {
+			t.Fatalf("Expected the clone to share nothing with the original, it shares %#x.", pointer)
+		}
+	}
+}
+
+func TestCloneMutation(t *testing.T) {
+	program := parseProgram(t, syntaxSample)
+	before := program.String()
+	encoded, _ := monkey.EncodeJson(program)
+
+	cloned := monkey.Clone(program).(*monkey.AstCompound)
+
+	monkey.Inspect(cloned, func(node monkey.AstNode) bool {
+		switch node := node.(type) {
+		case *monkey.AstIdentifier:
+			node.Value = "renamed"
+			node.Token.Literal = "renamed"
+			node.Token.Position.Line = 100
+		case *monkey.AstIntegerLiteral:
+			node.Value = 42
+			node.Token.Literal = "42"
+		case *monkey.AstFunctionCall:
+			node.Arguments = append(node.Arguments[:0], node.Arguments[len(node.Arguments)-1])
+		case *monkey.AstDocComment:
+			node.Tokens[0].Literal = "/// Changed."
+		}
+		return true
+	})
+	monkey.Modify(cloned, foldConstants)
+	cloned.Statements = cloned.Statements[:1]
+
+	if program.String() != before {
+		t.Fatalf("Expected the original to stay %q, got %q.", before, program.String())
+	}
+
+	after, _ := monkey.EncodeJson(program)
+	if string(after) != string(encoded) {
+		t.Fatal("Expected the tokens and positions of the original to be untouched.")
+	}
+}
+
+func TestClonePreservesSharing(t *testing.T) {
+	program := parseProgram(t, "f(1)")
+	cloned := monkey.Clone(program).(*monkey.AstCompound)
+
+	statement := cloned.Statements[0].(*monkey.AstExpressionStatement)
+	call := statement.Expression.(*monkey.AstFunctionCall)
+
+	if statement.Token != call.Token || call.Token != call.Identifier.Token {
+		t.Fatal("Expected the clone to share its tokens the way the original does.")
+	}
+
+	if call.Token == program.Statements[0].(*monkey.AstExpressionStatement).Token {
+		t.Fatal("Expected the clone to have its own tokens.")
+	}
+}
+
+func TestCloneSubtrees(t *testing.T) {
+	program := parseCollapsed(t, "let x = a + b * c")
+	value := program.Statements[0].(*monkey.AstLetStatement).Value
+
+	cloned := monkey.Clone(value)
+	if cloned == value || !monkey.Equal(cloned, value, monkey.EqualOptions{}) {
+		t.Fatalf("Expected an equal copy of %q, got %q.", value.String(), cloned.String())
+	}
+
+	if monkey.Clone(nil) != nil {
+		t.Fatal("Expected the clone of nil to be nil.")
+	}
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"monkey/monkey"
+	"testing"
+)
+
+func TestResolveDeclarations(t *testing.T) {
+	input := `isEven(10);
+fn isEven(n) { match (n) { 0 => true, _ => isOdd(n - 1) } }
+let x = 5;
+fn isOdd(n) { match (n) { 0 => false, _ => isEven(n - 1) } }
+`
+	lexer := monkey.NewLexer(input)
+	parser := monkey.NewParser(lexer)
+	compound := parser.Parse()
+
+	declarations, err := monkey.ResolveDeclarations(compound)
+	if err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	expectations := []string{"isEven", "isOdd"}
+
+	if len(declarations) != len(expectations) {
+		t.Fatalf(
+			"Expected %d declarations, got %d.",
+			len(expectations),
+			len(declarations),
+		)
+	}
+
+	for index, name := range expectations {
+		if declarations[index].Name.Value != name {
+			t.Fatalf(
+				"Expected declaration %d to be %q, got %q.",
+				index,
+				name,
+				declarations[index].Name.Value,
+			)
+		}
+	}
+}
+
+func TestResolveDeclarationsIsShallow(t *testing.T) {
+	input := `fn outer() {
+  fn inner() { 1 }
+  inner()
+}
+`
+	lexer := monkey.NewLexer(input)
+	parser := monkey.NewParser(lexer)
+	compound := parser.Parse()
+
+	declarations, err := monkey.ResolveDeclarations(compound)
+	if err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	if len(declarations) != 1 || declarations[0].Name.Value != "outer" {
+		t.Fatal("Expected only \"outer\" to be resolved in the program.")
+	}
+
+	inner, err := monkey.ResolveDeclarations(declarations[0].Function.Body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	if len(inner) != 1 || inner[0].Name.Value != "inner" {
+		t.Fatal("Expected \"inner\" to be resolved in the body of \"outer\".")
+	}
+}
+
+func TestResolveDuplicateDeclarations(t *testing.T) {
+	input := `fn f() { 1 }
+fn f() { 2 }
+`
+	lexer := monkey.NewLexer(input)
+	parser := monkey.NewParser(lexer)
+	compound := parser.Parse()
+
+	_, err := monkey.ResolveDeclarations(compound)
+	if err == nil {
+		t.Fatal("Expected an error for duplicate declarations, got none.")
+	}
+
+	expected := `function "f" is declared more than once`
+	if err.Error() != expected {
+		t.Fatalf("Expected %q, got %q.", expected, err.Error())
+	}
+}
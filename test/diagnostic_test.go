@@ -0,0 +1,225 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"monkey/monkey"
+	"strings"
+	"testing"
+)
+
+func renderDiagnostics(
+	t *testing.T,
+	source string,
+	format monkey.DiagnosticFormat,
+	diagnostics []*monkey.Diagnostic,
+) string {
+	var out bytes.Buffer
+	renderer := monkey.NewDiagnosticRenderer("main.monkey", source, format)
+	if err := renderer.Render(&out, diagnostics); err != nil {
+		t.Fatalf("Unexpected render error: %s", err)
+	}
+	return out.String()
+}
+
+func parseDiagnostics(source string) []*monkey.Diagnostic {
+	parser := monkey.NewParser(monkey.NewLexer(source))
+	parser.Parse()
+	return monkey.ParserDiagnostics(parser)
+}
+
+func TestPlainDiagnostics(t *testing.T) {
+	expectations := []struct {
+		source string
+		output string
+	}{
+		{
+			"let x = 5;\nlet y = 99999999999999999999;",
+			`main.monkey:2:9: error: could not parse "99999999999999999999" as an integer
+  |
+2 | let y = 99999999999999999999;
+  |         ^~~~~~~~~~~~~~~~~~~~
+`,
+		},
+		{
+			"let x = 5;\r\nlet y = 99999999999999999999;\r\n",
+			`main.monkey:2:9: error: could not parse "99999999999999999999" as an integer
+  |
+2 | let y = 99999999999999999999;
+  |         ^~~~~~~~~~~~~~~~~~~~
+`,
+		},
+		{
+			"\tlet = 5;",
+			`main.monkey:1:6: error: expected a name or a pattern after "let", got "="
+  |
+1 | 	let = 5;
+  | 	    ^
+`,
+		},
+		{
+			`let s = "abc`,
+			`main.monkey:1:9: error: illegal token "\"abc"
+  |
+1 | let s = "abc
+  |         ^~~~
+`,
+		},
+		{
+			"match (x) { 1 => 2 }",
+			`main.monkey:1:1: warning: match expression has no wildcard arm and may not be exhaustive
+  |
+1 | match (x) { 1 => 2 }
+  | ^~~~~
+`,
+		},
+	}
+
+	for _, expectation := range expectations {
+		diagnostics := parseDiagnostics(expectation.source)
+		if len(diagnostics) == 0 {
+			t.Fatalf("Expected diagnostics for %q, got none.", expectation.source)
+		}
+
+		output := renderDiagnostics(
+			t,
+			expectation.source,
+			monkey.DIAGNOSTIC_FORMAT_PLAIN,
+			diagnostics[:1],
+		)
+		if output != expectation.output {
+			t.Fatalf("Expected:\n%s\ngot:\n%s", expectation.output, output)
+		}
+	}
+}
+
+func TestDiagnosticLabelsAndNotes(t *testing.T) {
+	source := "fn f(a: int) -> int {\n  return true;\n}"
+	lexer := monkey.NewLexer(source)
+
+	tokens := []*monkey.Token{}
+	for token := lexer.Next(); token.Type != monkey.TOKEN_EOF; token = lexer.Next() {
+		tokens = append(tokens, token)
+	}
+
+	// "int" after the arrow, and "true" in the return statement
+	returnType, value := tokens[8], tokens[11]
+
+	diagnostic := monkey.NewDiagnostic(
+		monkey.SEVERITY_ERROR,
+		value,
+		"cannot return bool from a function returning int",
+	).WithLabel(returnType, "return type declared here").WithNote("annotations are optional")
+	diagnostic.Label = "this is a bool"
+
+	expected := `main.monkey:2:10: error: cannot return bool from a function returning int
+  |
+1 | fn f(a: int) -> int {
+  |                 --- return type declared here
+2 |   return true;
+  |          ^~~~ this is a bool
+  = note: annotations are optional
+`
+
+	output := renderDiagnostics(
+		t,
+		source,
+		monkey.DIAGNOSTIC_FORMAT_PLAIN,
+		[]*monkey.Diagnostic{diagnostic},
+	)
+	if output != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, output)
+	}
+}
+
+func TestDiagnosticsAtEndOfInput(t *testing.T) {
+	source := "let x = (1 + 2"
+
+	expected := `main.monkey:1:15: error: expected Close Paren, got "\x00"
+  |
+1 | let x = (1 + 2
+  |               ^
+`
+
+	output := renderDiagnostics(
+		t,
+		source,
+		monkey.DIAGNOSTIC_FORMAT_PLAIN,
+		parseDiagnostics(source)[:1],
+	)
+	if output != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, output)
+	}
+}
+
+func TestAnsiDiagnostics(t *testing.T) {
+	source := "let = 5;"
+
+	output := renderDiagnostics(
+		t,
+		source,
+		monkey.DIAGNOSTIC_FORMAT_ANSI,
+		parseDiagnostics(source),
+	)
+
+	if !strings.Contains(output, "\x1b[1m\x1b[31merror:\x1b[0m") {
+		t.Fatalf("Expected a red severity, got %q.", output)
+	}
+
+	if !strings.Contains(output, "let = 5;\n") {
+		t.Fatalf("Expected the source line uncoloured, got %q.", output)
+	}
+}
+
+func TestJsonDiagnostics(t *testing.T) {
+	source := "let x: int = \"a\";\nlet = 5;"
+
+	parser := monkey.NewParser(monkey.NewLexer(source))
+	program := parser.Parse()
+
+	diagnostics := monkey.ParserDiagnostics(parser)[:1]
+	diagnostics = append(diagnostics, monkey.CheckDiagnostics(monkey.Check(program))...)
+	diagnostics[0].WithNote("a name is required")
+
+	output := renderDiagnostics(t, source, monkey.DIAGNOSTIC_FORMAT_JSON, diagnostics)
+
+	var decoded []struct {
+		File     string
+		Severity string
+		Message  string
+		Span     struct {
+			Start struct{ Offset, Line, Column int }
+			End   struct{ Offset, Line, Column int }
+		}
+		Labels []any
+		Notes  []string
+	}
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %s: %s", err, output)
+	}
+
+	if len(decoded) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d.", len(decoded))
+	}
+
+	first, second := decoded[0], decoded[1]
+
+	if first.File != "main.monkey" || first.Severity != "error" ||
+		first.Message != `expected a name or a pattern after "let", got "="` {
+		t.Fatalf("Unexpected first diagnostic %+v.", first)
+	}
+
+	if first.Span.Start.Line != 2 || first.Span.Start.Column != 5 ||
+		first.Span.Start.Offset != 22 || first.Span.End.Column != 6 {
+		t.Fatalf("Unexpected first span %+v.", first.Span)
+	}
+
+	if len(first.Notes) != 1 || first.Notes[0] != "a name is required" || first.Labels == nil {
+		t.Fatalf("Unexpected first notes %v and labels %v.", first.Notes, first.Labels)
+	}
+
+	if second.Message != `cannot assign string to "x" of type int` ||
+		second.Span.Start.Line != 1 || second.Span.End.Column != 6 {
+		t.Fatalf("Unexpected second diagnostic %+v.", second)
+	}
+}
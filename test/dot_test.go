@@ -0,0 +1,96 @@
+package test
+
+import (
+	"monkey/monkey"
+	"strings"
+	"testing"
+)
+
+func TestRenderDot(t *testing.T) {
+	program := parseProgram(t, "-a + f(2, \"s\") * 3")
+
+	expected := `digraph ast {
+  node [shape=box, fontname="monospace"];
+  edge [fontname="monospace", fontsize=10];
+  n0 [label="block"];
+  n1 [label="expression"];
+  n2 [label="+"];
+  n3 [label="-"];
+  n4 [label="a"];
+  n5 [label="*"];
+  n6 [label="call"];
+  n7 [label="f"];
+  n8 [label="2"];
+  n9 [label="\"s\""];
+  n10 [label="3"];
+  n0 -> n1 [label="statements[0]"];
+  n1 -> n2 [label="expression"];
+  n2 -> n3 [label="left"];
+  n3 -> n4 [label="right"];
+  n2 -> n5 [label="right"];
+  n5 -> n6 [label="left"];
+  n6 -> n7 [label="identifier"];
+  n6 -> n8 [label="args[0]"];
+  n6 -> n9 [label="args[1]"];
+  n5 -> n10 [label="right"];
+}
+`
+
+	output := monkey.RenderDot(program, monkey.DotOptions{})
+	if output != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, output)
+	}
+}
+
+func TestRenderDotLabels(t *testing.T) {
+	expectations := []struct {
+		input  string
+		labels []string
+	}{
+		{"let [a, ...b] = 0..=2", []string{"let", "array pattern", "a", "...", "..="}},
+		{"xs |> map(f)", []string{"|>", "call", "map"}},
+		{"match (x) { _ => true }", []string{"match", "arm", "_", "true"}},
+		{"fn (...xs: [int]) -> bool { xs }", []string{"fn", "...param", "[ ]", "int", "bool"}},
+		{"/// Says \"hi\".\nfn hi() { 1 }", []string{"fn declaration", `Says \"hi\".`, "hi"}},
+	}
+
+	for _, expectation := range expectations {
+		output := monkey.RenderDot(parseProgram(t, expectation.input), monkey.DotOptions{})
+		for _, label := range expectation.labels {
+			if !strings.Contains(output, `[label="`+label+`"]`) {
+				t.Fatalf("Expected a node labelled %q for %q, got:\n%s", label, expectation.input, output)
+			}
+		}
+	}
+}
+
+func TestRenderDotClusters(t *testing.T) {
+	program := parseProgram(t, "let f = fn (a) { let g = macro(b) { b }; a }")
+
+	output := monkey.RenderDot(program, monkey.DotOptions{ClusterFunctions: true})
+
+	expected := `  subgraph cluster_6 {
+    label="fn";
+    n6 [label="block"];
+    n7 [label="let"];
+    n8 [label="g"];
+    n9 [label="macro"];
+    n10 [label="b"];
+    subgraph cluster_11 {
+      label="macro";
+      n11 [label="block"];
+      n12 [label="expression"];
+      n13 [label="b"];
+    }
+    n14 [label="expression"];
+    n15 [label="a"];
+  }
+`
+	if !strings.Contains(output, expected) {
+		t.Fatalf("Expected nested clusters:\n%s\ngot:\n%s", expected, output)
+	}
+
+	if strings.Contains(monkey.RenderDot(program, monkey.DotOptions{}), "subgraph") {
+		t.Fatal("Expected no clusters by default.")
+	}
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"monkey/monkey"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	expectations := []struct {
+		left    string
+		right   string
+		options monkey.EqualOptions
+		equal   bool
+	}{
+		{"a + b * c", "a + b * c", monkey.EqualOptions{}, true},
+		{"a + b * c", "a + (b * c)", monkey.EqualOptions{}, false},
+		{"a + b * c", "a + (b * c)", monkey.EqualOptions{IgnorePositions: true}, true},
+		{"a + b * c", "(a + b) * c", monkey.EqualOptions{IgnorePositions: true}, false},
+		{"let x = 1;", "let x = 1\n", monkey.EqualOptions{IgnorePositions: true}, true},
+		{"f(1, 2)", "f(1,\n  2,\n)", monkey.EqualOptions{IgnorePositions: true}, true},
+		{"fn (a) { a }", "fn (b) { b }", monkey.EqualOptions{IgnoreTokens: true}, false},
+		{"-1", "- 1", monkey.EqualOptions{IgnoreTokens: true}, true},
+		{"/// a\nlet x = 1", "///  a\nlet x = 1", monkey.EqualOptions{IgnorePositions: true}, false},
+	}
+
+	for _, expectation := range expectations {
+		left := parseCollapsed(t, expectation.left)
+		right := parseCollapsed(t, expectation.right)
+
+		if monkey.Equal(left, right, expectation.options) != expectation.equal {
+			t.Fatalf(
+				"Expected Equal(%q, %q, %+v) to be %t.",
+				expectation.left,
+				expectation.right,
+				expectation.options,
+				expectation.equal,
+			)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	expectations := []struct {
+		left   string
+		right  string
+		output string
+	}{
+		{
+			"let a = 1; let b = 2; let c = x + f(1, 2);",
+			"let a = 1; let b = 2; let c = x + f(1, 3);",
+			"Statements[2].Value.Right.Arguments[1].Token: token Integer \"2\" vs Integer \"3\"\n  - 2\n  + 3",
+		},
+		{
+			"a + b",
+			"a - b",
+			"Statements[0].Expression.Token: token Plus \"+\" vs Minus \"-\"\n  - (a + b)\n  + (a - b)",
+		},
+		{
+			"f(a, b)",
+			"f(a)",
+			"Statements[0].Expression.Arguments: 2 elements vs 1\n  - f(a, b)\n  + f(a)",
+		},
+		{
+			"return 1",
+			"return",
+			"Statements[0].Value: missing on the right\n  - return 1;\n  + return;",
+		},
+		{
+			"let x = a",
+			"let [x] = a",
+			"Statements[0].Identifier: missing on the right\n  - let x = a;\n  + let [x] = a;",
+		},
+		{
+			"x; 1",
+			"x; true",
+			"Statements[1].Token: token Integer \"1\" vs True \"true\"\n  - 1;\n  + true;",
+		},
+	}
+
+	for _, expectation := range expectations {
+		left := parseCollapsed(t, expectation.left)
+		right := parseCollapsed(t, expectation.right)
+
+		difference := monkey.Diff(left, right, monkey.EqualOptions{IgnorePositions: true})
+		if difference == nil {
+			t.Fatalf("Expected %q and %q to differ.", expectation.left, expectation.right)
+		}
+
+		if difference.String() != expectation.output {
+			t.Fatalf("Expected:\n%s\ngot:\n%s", expectation.output, difference.String())
+		}
+	}
+}
+
+func TestDiffPositions(t *testing.T) {
+	left := parseCollapsed(t, "a + b")
+	right := parseCollapsed(t, "a +\n  b")
+
+	difference := monkey.Diff(left, right, monkey.EqualOptions{})
+	if difference == nil {
+		t.Fatal("Expected the positions to differ.")
+	}
+
+	expected := "Statements[0].Expression.Right.Token: token \"b\" at 1:5 vs 2:3\n  - b\n  + b"
+	if difference.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, difference.String())
+	}
+
+	difference = monkey.Diff(
+		parseCollapsed(t, "x; 1"),
+		parseCollapsed(t, "x; true"),
+		monkey.EqualOptions{IgnoreTokens: true},
+	)
+	expected = "Statements[1].Expression: IntegerLiteral vs BooleanLiteral\n  - 1;\n  + true;"
+	if difference == nil || difference.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%v", expected, difference)
+	}
+
+	if monkey.Diff(left, left, monkey.EqualOptions{}) != nil {
+		t.Fatal("Expected a tree to equal itself.")
+	}
+}
@@ -0,0 +1,200 @@
+package test
+
+import (
+	"monkey/monkey"
+	"testing"
+)
+
+// expectFormat formats input, and checks that formatting the output again
+// changes nothing.
+func expectFormat(t *testing.T, input string, options monkey.FormatOptions, expected string) {
+	output := monkey.Format(parseProgram(t, input), options)
+	if output != expected {
+		t.Errorf("Wrong format for %q, expected:\n%s\ngot:\n%s", input, expected, output)
+		return
+	}
+
+	again := monkey.Format(parseProgram(t, output), options)
+	if again != output {
+		t.Errorf("Expected formatting %q to be idempotent, got:\n%s", output, again)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"", ""},
+		{"let five = 5;", "let five = 5\n"},
+		{"let a = 1; let b = 2;;", "let a = 1\nlet b = 2\n"},
+		{"return;", "return\n"},
+		{"return x", "return x\n"},
+		{"import \"lib/math\" as math", "import \"lib/math\" as math\n"},
+		{"export let x: [int] = xs", "export let x: [int] = xs\n"},
+		{"let x = 007", "let x = 7\n"},
+		{"a + (b * c)", "a + b * c\n"},
+		{"(a + b) * c", "(a + b) * c\n"},
+		{"a - (b - c)", "a - (b - c)\n"},
+		{"(a - b) - c", "a - b - c\n"},
+		{"((a))", "a\n"},
+		{"2 ** (3 ** 2)", "2 ** 3 ** 2\n"},
+		{"(2 ** 3) ** 2", "(2 ** 3) ** 2\n"},
+		{"-(a ** 2)", "-a ** 2\n"},
+		{"(-a) ** 2", "(-a) ** 2\n"},
+		{"2 ** -a", "2 ** -a\n"},
+		{"!(a == b)", "!(a == b)\n"},
+		{"-(-a)", "--a\n"},
+		{"(a < b) == (c < d)", "a < b == c < d\n"},
+		{"x in (0..10)", "x in 0..10\n"},
+		{"(0..10) |> (map(f))", "0..10 |> map(f)\n"},
+		{"(a |> f) + 1", "(a |> f) + 1\n"},
+		{"0..=(n - 1)", "0..=n - 1\n"},
+		{"f(...xs, (1))", "f(...xs, 1)\n"},
+		{"fn (x) { x * 2 }", "fn(x) { x * 2 }\n"},
+		{"fn () { }", "fn() {}\n"},
+		{"fn add(a: int, b = 1, ...rest) -> int { return a + b; }", "fn add(a: int, b = 1, ...rest) -> int { return a + b }\n"},
+		{"let f = fn(x) { let y = x; y }", "let f = fn(x) {\n  let y = x\n  y\n}\n"},
+		{"if (x) { 1 } else { 2 }", "if (x) { 1 } else { 2 }\n"},
+		{"match (x) { 1 => \"one\", -1 => \"minus one\", _ => \"other\" }", "match (x) { 1 => \"one\", -1 => \"minus one\", _ => \"other\" }\n"},
+		{"match (p) { [a, ...b] if a > 0 => a, {name, age: years, \"kind\": _} => name }", "match (p) { [a, ...b] if a > 0 => a, {name, age: years, \"kind\": _} => name }\n"},
+		{"let {name: name} = person", "let {name} = person\n"},
+		{"let m = macro(a, b) { quote(unquote(a) + unquote(b)) }", "let m = macro(a, b) { quote(unquote(a) + unquote(b)) }\n"},
+		{"let g: fn([int], bool) -> fn() = f", "let g: fn([int], bool) -> fn() = f\n"},
+	}
+
+	for _, expectation := range expectations {
+		expectFormat(t, expectation.input, monkey.FormatOptions{}, expectation.output)
+	}
+}
+
+func TestFormatLayout(t *testing.T) {
+	input := `/// Greets someone.
+///   Indented doc.
+fn greet(name) { let greeting = "hello "; return greeting }
+
+
+let a = 1
+/// Exported.
+export let b = 2
+let c = 3`
+
+	expected := `/// Greets someone.
+///   Indented doc.
+fn greet(name) {
+  let greeting = "hello "
+  return greeting
+}
+
+let a = 1
+/// Exported.
+export let b = 2
+let c = 3
+`
+
+	expectFormat(t, input, monkey.FormatOptions{}, expected)
+}
+
+func TestFormatLineLength(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{
+			"let result = compute(first_argument, second_argument)",
+			"let result = compute(\n  first_argument,\n  second_argument,\n)\n",
+		},
+		{
+			"let total = first_value + second_value * third_value - fourth_value",
+			"let total = first_value +\n  second_value * third_value -\n  fourth_value\n",
+		},
+		{
+			"xs |> filter(isEven) |> map(square) |> reduce(add, 0)",
+			"xs |>\n  filter(isEven) |>\n  map(square) |>\n  reduce(add, 0)\n",
+		},
+		{
+			"fn handler(request, response, ...middleware) { response }",
+			"fn handler(\n  request,\n  response,\n  ...middleware,\n) { response }\n",
+		},
+		{
+			"match (value) { 0 => \"zero\", [x, y] => x + y, _ => \"other\" }",
+			"match (value) {\n  0 => \"zero\",\n  [x, y] => x + y,\n  _ => \"other\",\n}\n",
+		},
+		{
+			"if (condition) { first_value } else { second_value }",
+			"if (condition) {\n  first_value\n} else {\n  second_value\n}\n",
+		},
+		{
+			"let [first_element, second_element, ...rest] = xs",
+			"let [\n  first_element,\n  second_element,\n  ...rest,\n] = xs\n",
+		},
+	}
+
+	for _, expectation := range expectations {
+		expectFormat(t, expectation.input, monkey.FormatOptions{LineLength: 40}, expectation.output)
+	}
+}
+
+func TestFormatIndentWidth(t *testing.T) {
+	input := "fn f(x) { if (x) { let y = 1; y } else { 0 } }"
+	expected := "fn f(x) {\n    if (x) {\n        let y = 1\n        y\n    } else {\n        0\n    }\n}\n"
+
+	expectFormat(t, input, monkey.FormatOptions{IndentWidth: 4}, expected)
+}
+
+func TestFormatNodes(t *testing.T) {
+	program := parseProgram(t, "let f = fn(a = 1 + 2) { a }")
+	function := program.Statements[0].(*monkey.AstLetStatement).Value.(*monkey.AstFunctionDefinition)
+
+	expectations := []struct {
+		node   monkey.AstNode
+		output string
+	}{
+		{program.Statements[0], "let f = fn(a = 1 + 2) { a }"},
+		{function.Params[0], "a = 1 + 2"},
+		{function.Body, "{ a }"},
+	}
+
+	for _, expectation := range expectations {
+		output := monkey.Format(expectation.node, monkey.FormatOptions{})
+		if output != expectation.output {
+			t.Errorf("Wrong format for %T, expected %q, got %q", expectation.node, expectation.output, output)
+		}
+	}
+}
+
+func TestFormatSource(t *testing.T) {
+	expectations := []struct {
+		input   string
+		options monkey.FormatOptions
+		output  string
+		message string
+	}{
+		{"let a = (1 + 2)", monkey.FormatOptions{}, "let a = 1 + 2\n", ""},
+		{"/// Kept.\nlet a = 1", monkey.FormatOptions{}, "/// Kept.\nlet a = 1\n", ""},
+		{
+			"let a = 1 // one\n// two\nlet b = 2",
+			monkey.FormatOptions{},
+			"",
+			"main.monkey:1:11: formatting would remove this comment and 1 more",
+		},
+		{"// one\nlet a = 1", monkey.FormatOptions{}, "", "main.monkey:1:1: formatting would remove this comment"},
+		{"let a = 1 // one\nlet b = 2", monkey.FormatOptions{DropComments: true}, "let a = 1\nlet b = 2\n", ""},
+		{"import 1", monkey.FormatOptions{}, "", `main.monkey:1:8: expected an import path string, got "1"`},
+	}
+
+	for _, expectation := range expectations {
+		output, err := monkey.FormatSource("main.monkey", expectation.input, expectation.options)
+
+		message := ""
+		if err != nil {
+			message = err.Error()
+		}
+		if message != expectation.message {
+			t.Errorf("Expected the error %q for %q, got %q", expectation.message, expectation.input, message)
+		}
+		if output != expectation.output {
+			t.Errorf("Wrong format for %q, expected:\n%s\ngot:\n%s", expectation.input, expectation.output, output)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package test
+
+import (
+	"monkey/monkey"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var fuzzSeeds = []string{
+	"",
+	"let five = 5;\nlet add = fn(x, y) { x + y; };\nadd(five, 10);",
+	"!-/*5; 5 < 10 > 5; 10 == 10; 10 != 9;",
+	"xs |> filter(isEven) |> map(square) |> sum",
+	`match (value) { 0 => "zero", [x, y] => x + y, {"kind": k} => k, n if n > 10 => "big", _ => "other" }`,
+	"let [first, second, ...rest] = xs; let {name, age: years} = person;",
+	"fn (a, b = 10, ...rest) { f(...args, 3) }",
+	"fn isEven(n) { if (n == 0) { true } else { isOdd(n - 1) } }",
+	"x in 0..10; 0..=n",
+	"let unless = macro(cond, cons, alt) { quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) }) };",
+	`import "lib/strings" as str; export let helper = fn() { 1 };`,
+	"let a = b\n(c)\nreturn\n",
+	"(a + b) * ((c))",
+	"add(1, 2",
+	"fn (",
+	"match (x) { [a, [b",
+	"let = ;",
+	"}}}))",
+	"\"unterminated",
+	"99999999999999999999",
+	strings.Repeat("fn a() {", monkey.MAX_NESTING_DEPTH+1),
+}
+
+func FuzzLexer(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		lexer := monkey.NewLexer(input)
+
+		// every token consumes at least one byte, except for the final Eof
+		for count := 0; count <= len(input)+1; count++ {
+			if lexer.Next().Type == monkey.TOKEN_EOF {
+				return
+			}
+		}
+
+		t.Fatalf("Expected the lexer to reach Eof for %q.", input)
+	})
+}
+
+func FuzzParser(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		lexer := monkey.NewLexer(input)
+		parser := monkey.NewParser(lexer)
+		compound := parser.Parse()
+
+		for _, statement := range compound.Statements {
+			if statement == nil {
+				t.Fatalf("Expected no nil statements for %q.", input)
+			}
+		}
+
+		_ = compound.String()
+
+		// JSON strings cannot hold invalid UTF-8
+		if utf8.ValidString(input) {
+			expectJsonRoundTrip(t, input, compound)
+		}
+	})
+}
+
+func FuzzStringRoundTrip(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		parser := monkey.NewParser(monkey.NewLexer(input))
+		parser.SetCollapseGroups(true)
+		compound := parser.Parse()
+		if len(parser.Errors()) > 0 {
+			return
+		}
+
+		printed := compound.String()
+
+		reparser := monkey.NewParser(monkey.NewLexer(printed))
+		reparser.SetCollapseGroups(true)
+		reparsed := reparser.Parse()
+		if len(reparser.Errors()) > 0 {
+			t.Fatalf(
+				"Expected %q printed from %q to parse, got %q.",
+				printed,
+				input,
+				reparser.Errors()[0],
+			)
+		}
+
+		if reparsed.String() != printed {
+			t.Fatalf(
+				"Expected %q printed from %q to round-trip, got %q.",
+				printed,
+				input,
+				reparsed.String(),
+			)
+		}
+	})
+}
+
+func FuzzFormat(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		parser := monkey.NewParser(monkey.NewLexer(input))
+		parser.SetCollapseGroups(true)
+		compound := parser.Parse()
+		if len(parser.Errors()) > 0 {
+			return
+		}
+
+		formatted := monkey.Format(compound, monkey.FormatOptions{})
+
+		reparser := monkey.NewParser(monkey.NewLexer(formatted))
+		reparser.SetCollapseGroups(true)
+		reparsed := reparser.Parse()
+		if len(reparser.Errors()) > 0 {
+			t.Fatalf(
+				"Expected %q formatted from %q to parse, got %q.",
+				formatted,
+				input,
+				reparser.Errors()[0],
+			)
+		}
+
+		options := monkey.EqualOptions{IgnorePositions: true, IgnoreTokens: true}
+		if difference := monkey.Diff(compound, reparsed, options); difference != nil {
+			t.Fatalf("Expected %q formatted from %q to keep the tree:\n%s", formatted, input, difference)
+		}
+
+		if again := monkey.Format(reparsed, monkey.FormatOptions{}); again != formatted {
+			t.Fatalf("Expected formatting %q to be idempotent, got %q.", formatted, again)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package test
+
+import (
+	"monkey/monkey"
+	"testing"
+)
+
+// syntaxSample uses every kind of node, for the tests of the passes that
+// must handle all of them.
+const syntaxSample = `import "lib/math" as math
+/// Documented.
+export let total: [int] = 0..10
+fn add(a: int, b = 1, ...rest) -> fn([int]) -> bool { return a + b }
+let [first, ...others] = xs
+let {name, age: _} = person
+let m = macro(x) { quote(unquote(x)) }
+let s = "text"
+-(1 + 2) |> add(...ys, "s") ** 2
+if (true) { 1 } else { 2 }
+match (x) { -1 => "one", [a] if a > 1 => a, _ => false }
+return`
+
+func parseProgram(t *testing.T, input string) *monkey.AstCompound {
+	return expectParsed(t, input, monkey.NewParser(monkey.NewLexer(input)))
+}
+
+func parseCollapsed(t *testing.T, input string) *monkey.AstCompound {
+	parser := monkey.NewParser(monkey.NewLexer(input))
+	parser.SetCollapseGroups(true)
+	return expectParsed(t, input, parser)
+}
+
+func checkProgram(t *testing.T, input string) []*monkey.CheckError {
+	return monkey.Check(parseProgram(t, input))
+}
+
+func expectParsed(t *testing.T, input string, parser *monkey.Parser) *monkey.AstCompound {
+	program := parser.Parse()
+
+	if len(parser.Errors()) > 0 {
+		t.Fatalf("Unexpected parser error for %q: %s", input, parser.Errors()[0])
+	}
+
+	return program
+}
+
+// expectErrorAt checks the message of an error or a warning and the
+// position of the token it was reported at.
+func expectErrorAt(t *testing.T, err error, token *monkey.Token, message string, line int, column int) {
+	position := token.Position
+	if err.Error() != message || position.Line != line || position.Column != column {
+		t.Fatalf(
+			"Expected %q at %d:%d, got %q at %d:%d.",
+			message,
+			line,
+			column,
+			err.Error(),
+			position.Line,
+			position.Column,
+		)
+	}
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"fmt"
+	"monkey/monkey"
+	"strings"
+	"testing"
+)
+
+func TestAstIndexIds(t *testing.T) {
+	program := parseProgram(t, "let f = fn(a) { a + 1 }\nf(2)")
+	index := monkey.NewAstIndex(program)
+
+	visited := []monkey.AstNode{}
+	monkey.Inspect(program, func(node monkey.AstNode) bool {
+		if node != nil {
+			visited = append(visited, node)
+		}
+		return true
+	})
+
+	if index.Len() != len(visited) {
+		t.Fatalf("Expected %d nodes, got %d", len(visited), index.Len())
+	}
+	for id, node := range visited {
+		if index.Node(id) != node {
+			t.Errorf("Expected node %d to be %T %q, got %T", id, node, node.String(), index.Node(id))
+		}
+		if actual, ok := index.Id(node); !ok || actual != id {
+			t.Errorf("Expected the ID of %T %q to be %d, got %d", node, node.String(), id, actual)
+		}
+	}
+
+	again := monkey.NewAstIndex(program)
+	for id := 0; id < index.Len(); id++ {
+		if again.Node(id) != index.Node(id) {
+			t.Errorf("Expected node %d to keep its ID when indexed again", id)
+		}
+	}
+
+	if _, ok := index.Id(&monkey.AstIdentifier{}); ok {
+		t.Errorf("Expected no ID for a node outside of the tree")
+	}
+}
+
+func TestAstIndexParents(t *testing.T) {
+	program := parseProgram(t, "let f = fn(a) { a + 1 }\nf(2)")
+	index := monkey.NewAstIndex(program)
+
+	function := program.Statements[0].(*monkey.AstLetStatement).Value.(*monkey.AstFunctionDefinition)
+	body := function.Body.Statements[0].(*monkey.AstExpressionStatement)
+	infix := body.Expression.(*monkey.AstInfixExpression)
+
+	if index.Parent(program) != nil {
+		t.Errorf("Expected the root to have no parent")
+	}
+	if index.Parent(infix.Right) != infix {
+		t.Errorf("Expected the parent of %q to be %q", infix.Right.String(), infix.String())
+	}
+	if index.Parent(program.Statements[1]) != program {
+		t.Errorf("Expected the parent of %q to be the program", program.Statements[1].String())
+	}
+
+	children := index.Children(function)
+	expected := []monkey.AstNode{function.Params[0], function.Body}
+	if fmt.Sprint(children) != fmt.Sprint(expected) {
+		t.Errorf("Expected the children of the function to be %v, got %v", expected, children)
+	}
+
+	if enclosing, ok := monkey.Enclosing[*monkey.AstFunctionDefinition](index, infix.Right); !ok || enclosing != function {
+		t.Errorf("Expected %q to be enclosed by the function", infix.Right.String())
+	}
+	if enclosing, ok := monkey.Enclosing[monkey.AstStatement](index, infix.Right); !ok || enclosing != body {
+		t.Errorf("Expected %q to be enclosed by the statement %q", infix.Right.String(), body.String())
+	}
+	if _, ok := monkey.Enclosing[*monkey.AstFunctionDefinition](index, program.Statements[1]); ok {
+		t.Errorf("Expected %q to be outside of any function", program.Statements[1].String())
+	}
+}
+
+func TestAstIndexHashPatternShorthand(t *testing.T) {
+	program := parseProgram(t, "let {name} = person")
+	index := monkey.NewAstIndex(program)
+
+	pattern := program.Statements[0].(*monkey.AstLetStatement).Pattern.(*monkey.AstHashPattern)
+	pair := pattern.Pairs[0]
+	binding := pair.Value.(*monkey.AstIdentifierPattern).Identifier
+
+	if index.Parent(pair.Key) != pair {
+		t.Errorf("Expected the key to be indexed under the pair")
+	}
+	if index.Parent(binding) != pair.Value {
+		t.Errorf("Expected the binding to be indexed under its pattern")
+	}
+}
+
+func TestAstIndexNodeAt(t *testing.T) {
+	input := `/// Adds.
+fn add(a, b) {
+  return a + b * 2
+}
+
+add(1, "two")
+`
+
+	expectations := []struct {
+		marker   string // the offset is at the first occurrence of the marker
+		delta    int
+		expected string // the type and the string of the node
+	}{
+		{"Adds", 0, "*monkey.AstDocComment /// Adds."},
+		{"add(a", 1, "*monkey.AstIdentifier add"},
+		{"b) {", 0, "*monkey.AstIdentifier b"},
+		{"b) {", 1, "*monkey.AstFunctionDefinition fn (a, b) { return (a + (b * 2)); }"},
+		{"return", 2, "*monkey.AstReturnStatement return (a + (b * 2));"},
+		{"+ b", 0, "*monkey.AstInfixExpression (a + (b * 2))"},
+		{"* 2", 0, "*monkey.AstInfixExpression (b * 2)"},
+		{"2\n", 0, "*monkey.AstIntegerLiteral 2"},
+		{"\n}", 1, "*monkey.AstCompound return (a + (b * 2));"},
+		{"\"two\"", 2, "*monkey.AstStringLiteral \"two\""},
+		{"add(1", 3, "*monkey.AstFunctionCall add(1, \"two\")"},
+		{"\n\nadd", 1, "*monkey.AstCompound " + parseProgram(t, input).String()},
+	}
+
+	program := parseProgram(t, input)
+	index := monkey.NewAstIndex(program)
+
+	for _, expectation := range expectations {
+		offset := strings.Index(input, expectation.marker) + expectation.delta
+		node := index.NodeAt(offset)
+		actual := "<nil>"
+		if node != nil {
+			actual = fmt.Sprintf("%T %s", node, node.String())
+		}
+		if actual != expectation.expected {
+			t.Errorf("Wrong node at %d (%q): expected %s, got %s", offset, input[offset:], expectation.expected, actual)
+		}
+	}
+
+	if node := index.NodeAt(len(input)); node != nil {
+		t.Errorf("Expected no node after the program, got %T", node)
+	}
+}
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"encoding/json"
+	"monkey/monkey"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func expectJsonRoundTrip(t *testing.T, input string, node monkey.AstNode) {
+	encoded, err := monkey.EncodeJson(node)
+	if err != nil {
+		t.Fatalf("Unexpected encoding error for %q: %s", input, err)
+	}
+
+	decoded, err := monkey.DecodeJson(encoded)
+	if err != nil {
+		t.Fatalf("Unexpected decoding error for %q: %s", input, err)
+	}
+
+	if !reflect.DeepEqual(decoded, node) {
+		t.Fatalf(
+			"Expected %q to survive a JSON round trip:\n%s",
+			input,
+			monkey.Diff(node, decoded, monkey.EqualOptions{}),
+		)
+	}
+}
+
+func TestJsonEncoding(t *testing.T) {
+	program := parseProgram(t, "let x = 5;")
+
+	encoded, err := monkey.EncodeJson(program)
+	if err != nil {
+		t.Fatalf("Unexpected encoding error: %s", err)
+	}
+
+	expected := `{"root":{"close":null,"kind":"Compound","statements":[{"doc":null,"identifier":{"kind":"Identifier","token":{"type":"Identifier","literal":"x","position":{"offset":4,"line":1,"column":5}},"value":"x"},"kind":"LetStatement","pattern":null,"token":{"type":"Let","literal":"let","position":{"offset":0,"line":1,"column":1}},"type":null,"value":{"kind":"IntegerLiteral","token":{"type":"Integer","literal":"5","position":{"offset":8,"line":1,"column":9}},"value":5}}],"token":null},"version":2}`
+	if string(encoded) != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, encoded)
+	}
+}
+
+func TestJsonRoundTrip(t *testing.T) {
+	inputs := []string{
+		syntaxSample,
+		"9223372036854775807",
+		"",
+		"let x = ;",
+	}
+
+	for _, input := range inputs {
+		parser := monkey.NewParser(monkey.NewLexer(input))
+		expectJsonRoundTrip(t, input, parser.Parse())
+	}
+}
+
+func TestJsonRoundTripDeepNesting(t *testing.T) {
+	inputs := []string{
+		strings.Repeat("fn a() {", 900) + strings.Repeat("}", 900),
+		"1" + strings.Repeat(" + 1", 900),
+	}
+
+	for _, input := range inputs {
+		start := time.Now()
+		expectJsonRoundTrip(t, input, parseProgram(t, input))
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected a deeply nested JSON round trip to take under a second, took %s", elapsed)
+		}
+	}
+}
+
+func TestJsonDecodingErrors(t *testing.T) {
+	expectations := []struct {
+		input   string
+		message string
+	}{
+		{`{"version":1,"root":null}`, "unsupported AST version 1, expected 2"},
+		{`{"version":2,"root":{"kind":"Nope"}}`, `unknown node kind "Nope"`},
+		{`{"version":2,"root":{"value":1}}`, "node without a kind"},
+		{
+			`{"version":2,"root":{"kind":"Identifier","token":null,"value":"x","extra":1}}`,
+			`unknown field "extra" in Identifier`,
+		},
+		{
+			`{"version":2,"root":{"kind":"Compound","statements":[{"kind":"Identifier","token":null,"value":"x"}]}}`,
+			"Compound.statements: Identifier is not Statement",
+		},
+		{
+			`{"version":2,"root":{"kind":"LetStatement","identifier":{"kind":"IntegerLiteral","token":null,"value":1}}}`,
+			"LetStatement.identifier: IntegerLiteral is not Identifier",
+		},
+		{
+			`{"version":2,"root":{"kind":"Identifier","token":{"type":"Nope","literal":"x","position":{}},"value":"x"}}`,
+			`Identifier.token: unknown token type "Nope"`,
+		},
+	}
+
+	for _, expectation := range expectations {
+		_, err := monkey.DecodeJson([]byte(expectation.input))
+		if err == nil {
+			t.Fatalf("Expected an error for %s, got none.", expectation.input)
+		}
+
+		if err.Error() != expectation.message {
+			t.Fatalf("Expected %q, got %q.", expectation.message, err.Error())
+		}
+	}
+}
+
+func TestJsonSchema(t *testing.T) {
+	var schema struct {
+		Properties struct {
+			Version struct {
+				Const int
+			}
+		}
+		Defs map[string]any `json:"$defs"`
+	}
+	if err := json.Unmarshal(monkey.AstJsonSchema(), &schema); err != nil {
+		t.Fatalf("Expected a valid JSON schema: %s", err)
+	}
+
+	if schema.Properties.Version.Const != monkey.AST_JSON_VERSION {
+		t.Fatalf("Expected version %d, got %d.", monkey.AST_JSON_VERSION, schema.Properties.Version.Const)
+	}
+
+	// every kind produced by the encoder is defined
+	program := parseProgram(t, `fn f(a: [int]) -> bool { match (a) { [x, ...y] => 1, {k: v} => 2 } }`)
+	monkey.Inspect(program, func(node monkey.AstNode) bool {
+		if node != nil {
+			kind := strings.TrimPrefix(reflect.TypeOf(node).Elem().Name(), "Ast")
+			if _, ok := schema.Defs[kind]; !ok {
+				t.Fatalf("Expected the schema to define %q.", kind)
+			}
+		}
+		return true
+	})
+}
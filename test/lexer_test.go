@@ -96,6 +96,7 @@ if (5 < 10) {
 		{monkey.TOKEN_FALSE, "false"},
 		{monkey.TOKEN_SEMICOLON, ";"},
 		{monkey.TOKEN_CLOSE_BRACE, "}"},
+		{monkey.TOKEN_SEMICOLON, "\n"},
 		{monkey.TOKEN_INTEGER, "10"},
 		{monkey.TOKEN_EQUALS, "=="},
 		{monkey.TOKEN_INTEGER, "10"},
@@ -131,3 +132,399 @@ if (5 < 10) {
 		}
 	}
 }
+
+type lexerHelpers struct{}
+
+func (*lexerHelpers) expectTokens(
+	t *testing.T,
+	input string,
+	tests []struct {
+		tokenType monkey.TokenType
+		literal   string
+	},
+) {
+	lexer := monkey.NewLexer(input)
+
+	for index, expected := range tests {
+		token := lexer.Next()
+
+		if token.Type != expected.tokenType {
+			t.Fatalf(
+				"tests[%d] - TokenType wrong. expect=%q, got=%q,",
+				index,
+				monkey.GetTokenTypeString(expected.tokenType),
+				monkey.GetTokenTypeString(token.Type),
+			)
+		}
+
+		if token.Literal != expected.literal {
+			t.Fatalf(
+				"tests[%d] - TokenLiteral wrong. expect=%q, got=%q,",
+				index,
+				expected.literal,
+				token.Literal,
+			)
+		}
+	}
+}
+
+func TestPipeToken(t *testing.T) {
+	input := `xs |> sum | x`
+
+	tests := []struct {
+		tokenType monkey.TokenType
+		literal   string
+	}{
+		{monkey.TOKEN_IDENTIFIER, "xs"},
+		{monkey.TOKEN_PIPE, "|>"},
+		{monkey.TOKEN_IDENTIFIER, "sum"},
+		{monkey.TOKEN_ILLEGAL, "|"},
+		{monkey.TOKEN_IDENTIFIER, "x"},
+		{monkey.TOKEN_EOF, "\x00"},
+	}
+
+	helpers := &lexerHelpers{}
+	helpers.expectTokens(t, input, tests)
+}
+
+func TestMatchTokens(t *testing.T) {
+	input := `match (value) {
+  0 => "zero",
+  [x, y] => x + y,
+  {"kind": k} => k,
+  _ => "other"
+}
+"unterminated`
+
+	tests := []struct {
+		tokenType monkey.TokenType
+		literal   string
+	}{
+		{monkey.TOKEN_MATCH, "match"},
+		{monkey.TOKEN_OPEN_PAREN, "("},
+		{monkey.TOKEN_IDENTIFIER, "value"},
+		{monkey.TOKEN_CLOSE_PAREN, ")"},
+		{monkey.TOKEN_OPEN_BRACE, "{"},
+		{monkey.TOKEN_INTEGER, "0"},
+		{monkey.TOKEN_FAT_ARROW, "=>"},
+		{monkey.TOKEN_STRING, "zero"},
+		{monkey.TOKEN_COMMA, ","},
+		{monkey.TOKEN_OPEN_BRACKET, "["},
+		{monkey.TOKEN_IDENTIFIER, "x"},
+		{monkey.TOKEN_COMMA, ","},
+		{monkey.TOKEN_IDENTIFIER, "y"},
+		{monkey.TOKEN_CLOSE_BRACKET, "]"},
+		{monkey.TOKEN_FAT_ARROW, "=>"},
+		{monkey.TOKEN_IDENTIFIER, "x"},
+		{monkey.TOKEN_PLUS, "+"},
+		{monkey.TOKEN_IDENTIFIER, "y"},
+		{monkey.TOKEN_COMMA, ","},
+		{monkey.TOKEN_OPEN_BRACE, "{"},
+		{monkey.TOKEN_STRING, "kind"},
+		{monkey.TOKEN_COLON, ":"},
+		{monkey.TOKEN_IDENTIFIER, "k"},
+		{monkey.TOKEN_CLOSE_BRACE, "}"},
+		{monkey.TOKEN_FAT_ARROW, "=>"},
+		{monkey.TOKEN_IDENTIFIER, "k"},
+		{monkey.TOKEN_COMMA, ","},
+		{monkey.TOKEN_IDENTIFIER, "_"},
+		{monkey.TOKEN_FAT_ARROW, "=>"},
+		{monkey.TOKEN_STRING, "other"},
+		{monkey.TOKEN_CLOSE_BRACE, "}"},
+		{monkey.TOKEN_SEMICOLON, "\n"},
+		{monkey.TOKEN_ILLEGAL, "\"unterminated"},
+		{monkey.TOKEN_EOF, "\x00"},
+	}
+
+	helpers := &lexerHelpers{}
+	helpers.expectTokens(t, input, tests)
+}
+
+func TestEllipsisToken(t *testing.T) {
+	input := `let [first, ...rest] = xs; .`
+
+	tests := []struct {
+		tokenType monkey.TokenType
+		literal   string
+	}{
+		{monkey.TOKEN_LET, "let"},
+		{monkey.TOKEN_OPEN_BRACKET, "["},
+		{monkey.TOKEN_IDENTIFIER, "first"},
+		{monkey.TOKEN_COMMA, ","},
+		{monkey.TOKEN_ELLIPSIS, "..."},
+		{monkey.TOKEN_IDENTIFIER, "rest"},
+		{monkey.TOKEN_CLOSE_BRACKET, "]"},
+		{monkey.TOKEN_ASSIGNMENT, "="},
+		{monkey.TOKEN_IDENTIFIER, "xs"},
+		{monkey.TOKEN_SEMICOLON, ";"},
+		{monkey.TOKEN_ILLEGAL, "."},
+		{monkey.TOKEN_EOF, "\x00"},
+	}
+
+	helpers := &lexerHelpers{}
+	helpers.expectTokens(t, input, tests)
+}
+
+func TestRangeTokens(t *testing.T) {
+	input := `0..10; 1..=2; x in 0..n; 1...2`
+
+	tests := []struct {
+		tokenType monkey.TokenType
+		literal   string
+	}{
+		{monkey.TOKEN_INTEGER, "0"},
+		{monkey.TOKEN_RANGE, ".."},
+		{monkey.TOKEN_INTEGER, "10"},
+		{monkey.TOKEN_SEMICOLON, ";"},
+		{monkey.TOKEN_INTEGER, "1"},
+		{monkey.TOKEN_RANGE_INCLUSIVE, "..="},
+		{monkey.TOKEN_INTEGER, "2"},
+		{monkey.TOKEN_SEMICOLON, ";"},
+		{monkey.TOKEN_IDENTIFIER, "x"},
+		{monkey.TOKEN_IN, "in"},
+		{monkey.TOKEN_INTEGER, "0"},
+		{monkey.TOKEN_RANGE, ".."},
+		{monkey.TOKEN_IDENTIFIER, "n"},
+		{monkey.TOKEN_SEMICOLON, ";"},
+		{monkey.TOKEN_INTEGER, "1"},
+		{monkey.TOKEN_ELLIPSIS, "..."},
+		{monkey.TOKEN_INTEGER, "2"},
+		{monkey.TOKEN_EOF, "\x00"},
+	}
+
+	helpers := &lexerHelpers{}
+	helpers.expectTokens(t, input, tests)
+}
+
+func TestAutomaticSemicolons(t *testing.T) {
+	input := `let a = b
+(c)
+return
+x +
+y
+add(
+  1,
+  2
+)
+"s"
+-1
+}
+`
+
+	tests := []struct {
+		tokenType monkey.TokenType
+		literal   string
+	}{
+		{monkey.TOKEN_LET, "let"},
+		{monkey.TOKEN_IDENTIFIER, "a"},
+		{monkey.TOKEN_ASSIGNMENT, "="},
+		{monkey.TOKEN_IDENTIFIER, "b"},
+		{monkey.TOKEN_SEMICOLON, "\n"},
+		{monkey.TOKEN_OPEN_PAREN, "("},
+		{monkey.TOKEN_IDENTIFIER, "c"},
+		{monkey.TOKEN_CLOSE_PAREN, ")"},
+		{monkey.TOKEN_SEMICOLON, "\n"},
+		{monkey.TOKEN_RETURN, "return"},
+		{monkey.TOKEN_SEMICOLON, "\n"},
+		{monkey.TOKEN_IDENTIFIER, "x"},
+		{monkey.TOKEN_PLUS, "+"},
+		{monkey.TOKEN_IDENTIFIER, "y"},
+		{monkey.TOKEN_SEMICOLON, "\n"},
+		{monkey.TOKEN_IDENTIFIER, "add"},
+		{monkey.TOKEN_OPEN_PAREN, "("},
+		{monkey.TOKEN_INTEGER, "1"},
+		{monkey.TOKEN_COMMA, ","},
+		{monkey.TOKEN_INTEGER, "2"},
+		{monkey.TOKEN_CLOSE_PAREN, ")"},
+		{monkey.TOKEN_SEMICOLON, "\n"},
+		{monkey.TOKEN_STRING, "s"},
+		{monkey.TOKEN_SEMICOLON, "\n"},
+		{monkey.TOKEN_MINUS, "-"},
+		{monkey.TOKEN_INTEGER, "1"},
+		{monkey.TOKEN_CLOSE_BRACE, "}"},
+		{monkey.TOKEN_EOF, "\x00"},
+	}
+
+	helpers := &lexerHelpers{}
+	helpers.expectTokens(t, input, tests)
+}
+
+func TestEmptyInput(t *testing.T) {
+	tests := []struct {
+		tokenType monkey.TokenType
+		literal   string
+	}{
+		{monkey.TOKEN_EOF, "\x00"},
+		{monkey.TOKEN_EOF, "\x00"},
+	}
+
+	helpers := &lexerHelpers{}
+	helpers.expectTokens(t, "", tests)
+}
+
+func TestNulBytes(t *testing.T) {
+	input := "a\x00b \"c\x00d\""
+
+	tests := []struct {
+		tokenType monkey.TokenType
+		literal   string
+	}{
+		{monkey.TOKEN_IDENTIFIER, "a"},
+		{monkey.TOKEN_ILLEGAL, "\x00"},
+		{monkey.TOKEN_IDENTIFIER, "b"},
+		{monkey.TOKEN_STRING, "c\x00d"},
+		{monkey.TOKEN_EOF, "\x00"},
+	}
+
+	helpers := &lexerHelpers{}
+	helpers.expectTokens(t, input, tests)
+}
+
+func TestPowerToken(t *testing.T) {
+	input := `2 ** 3 * 4 *** 5`
+
+	tests := []struct {
+		tokenType monkey.TokenType
+		literal   string
+	}{
+		{monkey.TOKEN_INTEGER, "2"},
+		{monkey.TOKEN_POWER, "**"},
+		{monkey.TOKEN_INTEGER, "3"},
+		{monkey.TOKEN_ASTERISK, "*"},
+		{monkey.TOKEN_INTEGER, "4"},
+		{monkey.TOKEN_POWER, "**"},
+		{monkey.TOKEN_ASTERISK, "*"},
+		{monkey.TOKEN_INTEGER, "5"},
+		{monkey.TOKEN_EOF, "\x00"},
+	}
+
+	helpers := &lexerHelpers{}
+	helpers.expectTokens(t, input, tests)
+}
+
+func TestArrowToken(t *testing.T) {
+	input := `fn (a: int) -> bool`
+
+	tests := []struct {
+		tokenType monkey.TokenType
+		literal   string
+	}{
+		{monkey.TOKEN_FUNCTION, "fn"},
+		{monkey.TOKEN_OPEN_PAREN, "("},
+		{monkey.TOKEN_IDENTIFIER, "a"},
+		{monkey.TOKEN_COLON, ":"},
+		{monkey.TOKEN_IDENTIFIER, "int"},
+		{monkey.TOKEN_CLOSE_PAREN, ")"},
+		{monkey.TOKEN_ARROW, "->"},
+		{monkey.TOKEN_IDENTIFIER, "bool"},
+		{monkey.TOKEN_EOF, "\x00"},
+	}
+
+	helpers := &lexerHelpers{}
+	helpers.expectTokens(t, input, tests)
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  foo(\"bar\")"
+
+	tests := []struct {
+		literal  string
+		position monkey.Position
+	}{
+		{"let", monkey.Position{Offset: 0, Line: 1, Column: 1}},
+		{"x", monkey.Position{Offset: 4, Line: 1, Column: 5}},
+		{"=", monkey.Position{Offset: 6, Line: 1, Column: 7}},
+		{"5", monkey.Position{Offset: 8, Line: 1, Column: 9}},
+		{";", monkey.Position{Offset: 9, Line: 1, Column: 10}},
+		{"foo", monkey.Position{Offset: 13, Line: 2, Column: 3}},
+		{"(", monkey.Position{Offset: 16, Line: 2, Column: 6}},
+		{"bar", monkey.Position{Offset: 17, Line: 2, Column: 7}},
+		{")", monkey.Position{Offset: 22, Line: 2, Column: 12}},
+	}
+
+	lexer := monkey.NewLexer(input)
+
+	for index, expected := range tests {
+		token := lexer.Next()
+
+		if token.Literal != expected.literal {
+			t.Fatalf(
+				"tests[%d] - TokenLiteral wrong. expect=%q, got=%q,",
+				index,
+				expected.literal,
+				token.Literal,
+			)
+		}
+
+		if token.Position != expected.position {
+			t.Fatalf(
+				"tests[%d] - Position wrong. expect=%+v, got=%+v,",
+				index,
+				expected.position,
+				token.Position,
+			)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// a plain comment
+/// Adds two numbers.
+///
+let add = fn (a, b) { a / b } // trailing
+//// not a doc comment
+let x = 1 /// trailing doc
+/// last`
+
+	tests := []struct {
+		tokenType monkey.TokenType
+		literal   string
+	}{
+		{monkey.TOKEN_DOC_COMMENT, "/// Adds two numbers."},
+		{monkey.TOKEN_DOC_COMMENT, "///"},
+		{monkey.TOKEN_LET, "let"},
+		{monkey.TOKEN_IDENTIFIER, "add"},
+		{monkey.TOKEN_ASSIGNMENT, "="},
+		{monkey.TOKEN_FUNCTION, "fn"},
+		{monkey.TOKEN_OPEN_PAREN, "("},
+		{monkey.TOKEN_IDENTIFIER, "a"},
+		{monkey.TOKEN_COMMA, ","},
+		{monkey.TOKEN_IDENTIFIER, "b"},
+		{monkey.TOKEN_CLOSE_PAREN, ")"},
+		{monkey.TOKEN_OPEN_BRACE, "{"},
+		{monkey.TOKEN_IDENTIFIER, "a"},
+		{monkey.TOKEN_SLASH, "/"},
+		{monkey.TOKEN_IDENTIFIER, "b"},
+		{monkey.TOKEN_CLOSE_BRACE, "}"},
+		{monkey.TOKEN_SEMICOLON, "\n"},
+		{monkey.TOKEN_LET, "let"},
+		{monkey.TOKEN_IDENTIFIER, "x"},
+		{monkey.TOKEN_ASSIGNMENT, "="},
+		{monkey.TOKEN_INTEGER, "1"},
+		{monkey.TOKEN_DOC_COMMENT, "/// trailing doc"},
+		// only comments follow, so this is the end of the content
+		{monkey.TOKEN_DOC_COMMENT, "/// last"},
+		{monkey.TOKEN_EOF, "\x00"},
+	}
+
+	helpers := &lexerHelpers{}
+	helpers.expectTokens(t, input, tests)
+}
+
+func TestCommentsBeforeClosingDelimiters(t *testing.T) {
+	input := "f(\n  a // first\n  // nothing else\n)"
+
+	tests := []struct {
+		tokenType monkey.TokenType
+		literal   string
+	}{
+		{monkey.TOKEN_IDENTIFIER, "f"},
+		{monkey.TOKEN_OPEN_PAREN, "("},
+		{monkey.TOKEN_IDENTIFIER, "a"},
+		{monkey.TOKEN_CLOSE_PAREN, ")"},
+		{monkey.TOKEN_EOF, "\x00"},
+	}
+
+	helpers := &lexerHelpers{}
+	helpers.expectTokens(t, input, tests)
+}
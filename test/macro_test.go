@@ -0,0 +1,187 @@
+package test
+
+import (
+	"monkey/monkey"
+	"testing"
+)
+
+func TestDefineMacros(t *testing.T) {
+	input := `let number = 1;
+let function = fn(x, y) { x + y };
+let mymacro = macro(x, y) { quote(x + y); };
+`
+	lexer := monkey.NewLexer(input)
+	parser := monkey.NewParser(lexer)
+	program := parser.Parse()
+
+	macros := monkey.DefineMacros(program)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("Expected 2 statements, got %d.", len(program.Statements))
+	}
+
+	if _, ok := macros["number"]; ok {
+		t.Fatal("Expected \"number\" not to be a macro.")
+	}
+
+	if _, ok := macros["function"]; ok {
+		t.Fatal("Expected \"function\" not to be a macro.")
+	}
+
+	macro, ok := macros["mymacro"]
+	if !ok {
+		t.Fatal("Expected \"mymacro\" to be defined.")
+	}
+
+	if len(macro.Params) != 2 {
+		t.Fatalf("Expected 2 macro params, got %d.", len(macro.Params))
+	}
+
+	expectedBody := "quote((x + y));"
+	if macro.Body.String() != expectedBody {
+		t.Fatalf("Expected %q, got %q.", expectedBody, macro.Body.String())
+	}
+}
+
+func TestExpandMacros(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{
+			`let infixExpression = macro() { quote(1 + 2); };
+infixExpression();`,
+			"(1 + 2);",
+		},
+		{
+			`let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+reverse(2 + 2, 10 - 5);`,
+			"((10 - 5) - (2 + 2));",
+		},
+		{
+			`let unless = macro(cond, cons, alt) {
+  quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) })
+};
+unless(10 > 5, puts("not greater"), puts("greater"));`,
+			`if ((!(10 > 5))) { puts("not greater"); } else { puts("greater"); };`,
+		},
+		{
+			`let twice = macro(x) { return quote(add(unquote(x), unquote(x))); };
+let y = fn() { twice(twice(1)) };`,
+			"let y = fn () { add(add(1, 1), add(1, 1)); };",
+		},
+	}
+
+	for _, expectation := range expectations {
+		lexer := monkey.NewLexer(expectation.input)
+		parser := monkey.NewParser(lexer)
+		program := parser.Parse()
+
+		macros := monkey.DefineMacros(program)
+		expanded, err := monkey.ExpandMacros(program, macros)
+		if err != nil {
+			t.Fatalf("Expected no error, got %q.", err)
+		}
+
+		if expanded.String() != expectation.output {
+			t.Fatalf("Expected %q, got %q.", expectation.output, expanded.String())
+		}
+	}
+}
+
+func TestExpandMacrosLeavesTemplateUntouched(t *testing.T) {
+	input := `let double = macro(x) { quote(unquote(x) * 2); };
+double(1);
+double(2);`
+
+	lexer := monkey.NewLexer(input)
+	parser := monkey.NewParser(lexer)
+	program := parser.Parse()
+
+	macros := monkey.DefineMacros(program)
+	expanded, err := monkey.ExpandMacros(program, macros)
+	if err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	expected := "(1 * 2);(2 * 2);"
+	if expanded.String() != expected {
+		t.Fatalf("Expected %q, got %q.", expected, expanded.String())
+	}
+
+	expectedProgram := "double(1);double(2);"
+	if program.String() != expectedProgram {
+		t.Fatalf("Expected %q, got %q.", expectedProgram, program.String())
+	}
+}
+
+func TestExpandMacrosCopiesArguments(t *testing.T) {
+	input := `let twice = macro(x) { quote(unquote(x) + unquote(x)); };
+twice(f(1));`
+
+	lexer := monkey.NewLexer(input)
+	parser := monkey.NewParser(lexer)
+	program := parser.Parse()
+
+	macros := monkey.DefineMacros(program)
+	expanded, err := monkey.ExpandMacros(program, macros)
+	if err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	statement := expanded.Statements[0].(*monkey.AstExpressionStatement)
+	infix := statement.Expression.(*monkey.AstInfixExpression)
+	if infix.Left == infix.Right {
+		t.Fatal("Expected every unquote to get its own copy of the argument.")
+	}
+
+	original := program.Statements[0].(*monkey.AstExpressionStatement).Expression
+	if infix.Left == original.(*monkey.AstFunctionCall).Arguments[0] {
+		t.Fatal("Expected the expansion not to share the argument with the program.")
+	}
+
+	infix.Left.(*monkey.AstFunctionCall).Identifier.Token.Literal = "g"
+	if expanded.String() != "(g(1) + f(1));" || program.String() != "twice(f(1));" {
+		t.Fatalf("Expected only one copy to change, got %q.", expanded.String())
+	}
+}
+
+func TestExpandMacrosErrors(t *testing.T) {
+	expectations := []struct {
+		input   string
+		message string
+	}{
+		{
+			`let m = macro(a) { quote(a) }; m(1, 2);`,
+			`macro "m" expects 1 arguments, got 2`,
+		},
+		{
+			`let m = macro(a) { a }; m(1);`,
+			`macro "m" must return a quote(...) expression`,
+		},
+		{
+			`let m = macro(a) { quote(unquote(b)) }; m(1);`,
+			`macro "m" has no parameter "b" to unquote`,
+		},
+		{
+			`let m = macro(a) { quote(unquote(a + 1)) }; m(1);`,
+			`macro "m" can only unquote its parameters, got (a + 1)`,
+		},
+	}
+
+	for _, expectation := range expectations {
+		lexer := monkey.NewLexer(expectation.input)
+		parser := monkey.NewParser(lexer)
+		program := parser.Parse()
+
+		macros := monkey.DefineMacros(program)
+		_, err := monkey.ExpandMacros(program, macros)
+		if err == nil {
+			t.Fatalf("Expected an error for %q, got none.", expectation.input)
+		}
+
+		if err.Error() != expectation.message {
+			t.Fatalf("Expected %q, got %q.", expectation.message, err.Error())
+		}
+	}
+}
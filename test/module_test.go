@@ -0,0 +1,126 @@
+package test
+
+import (
+	"errors"
+	"monkey/monkey"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestModuleResolver(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.monkey": {Data: []byte(`import "lib/strings" as str;
+import "lib/math";
+upper("hi");
+`)},
+		"lib/strings.monkey": {Data: []byte(`import "lib/math" as math;
+export let upper = fn(s) { s };
+export fn lower(s) { s }
+let private = 1;
+`)},
+		"lib/math.monkey": {Data: []byte(`export let [pi, e] = constants();`)},
+	}
+
+	resolver := monkey.NewModuleResolver(fsys)
+
+	main, err := resolver.Resolve("main")
+	if err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+
+	if len(main.Imports) != 2 {
+		t.Fatalf("Expected 2 imports, got %d.", len(main.Imports))
+	}
+
+	strs := main.Imports[0]
+	if strs.Path != "lib/strings" {
+		t.Fatalf("Expected first import to be \"lib/strings\", got %q.", strs.Path)
+	}
+
+	for _, name := range []string{"upper", "lower"} {
+		if _, ok := strs.Exports[name]; !ok {
+			t.Fatalf("Expected %q to be exported.", name)
+		}
+	}
+
+	if _, ok := strs.Exports["private"]; ok {
+		t.Fatal("Expected \"private\" not to be exported.")
+	}
+
+	math := main.Imports[1]
+	if strs.Imports[0] != math {
+		t.Fatal("Expected \"lib/math\" to be parsed once and cached.")
+	}
+
+	for _, name := range []string{"pi", "e"} {
+		if _, ok := math.Exports[name]; !ok {
+			t.Fatalf("Expected %q to be exported.", name)
+		}
+	}
+
+	cached, err := resolver.Resolve("lib/strings")
+	if err != nil {
+		t.Fatalf("Expected no error, got %q.", err)
+	}
+	if cached != strs {
+		t.Fatal("Expected \"lib/strings\" to be served from the cache.")
+	}
+}
+
+func TestModuleResolverErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.monkey":      {Data: []byte(`import "b";`)},
+		"b.monkey":      {Data: []byte(`import "c";`)},
+		"c.monkey":      {Data: []byte(`import "a";`)},
+		"self.monkey":   {Data: []byte(`import "self";`)},
+		"broken.monkey": {Data: []byte(`import 1;`)},
+		"user.monkey":   {Data: []byte(`import "missing";`)},
+	}
+
+	expectations := []struct {
+		path    string
+		message string
+	}{
+		{"a", "import cycle: a -> b -> c -> a"},
+		{"self", "import cycle: self -> self"},
+		{"broken", `broken:1:8: expected an import path string, got "1"`},
+		{"user", `cannot import "missing"`},
+		{"../outside", `invalid import path "../outside"`},
+	}
+
+	for _, expectation := range expectations {
+		resolver := monkey.NewModuleResolver(fsys)
+
+		_, err := resolver.Resolve(expectation.path)
+		if err == nil {
+			t.Fatalf("Expected an error for %q, got none.", expectation.path)
+		}
+
+		if !strings.HasPrefix(err.Error(), expectation.message) {
+			t.Fatalf("Expected %q, got %q.", expectation.message, err.Error())
+		}
+	}
+}
+
+func TestModuleResolverParseErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"broken.monkey": {Data: []byte("import 1\nimport 2\n")},
+	}
+
+	_, err := monkey.NewModuleResolver(fsys).Resolve("broken")
+	if err == nil {
+		t.Fatal("Expected an error, got none.")
+	}
+
+	expected := "broken:1:8: expected an import path string, got \"1\"\n" +
+		"broken:2:8: expected an import path string, got \"2\""
+	if err.Error() != expected {
+		t.Fatalf("Expected %q, got %q.", expected, err.Error())
+	}
+
+	var parseError *monkey.ParseError
+	if !errors.As(err, &parseError) || parseError.Token.Position.Line != 1 {
+		t.Fatalf("Expected the first *monkey.ParseError to be reachable, got %v.", parseError)
+	}
+}
@@ -2,6 +2,7 @@ package test
 
 import (
 	"monkey/monkey"
+	"strings"
 	"testing"
 )
 
@@ -138,6 +139,64 @@ func (*parserHelpers) expectBooleanLiteral(
 	return booleanLiteral
 }
 
+func (*parserHelpers) expectOutputs(
+	t *testing.T,
+	expectations []struct {
+		input  string
+		output string
+	},
+) {
+	for _, expectation := range expectations {
+		lexer := monkey.NewLexer(expectation.input)
+		parser := monkey.NewParser(lexer)
+		compound := parser.Parse()
+
+		if len(compound.Statements) != 1 {
+			t.Fatalf("Expected 1 statement, got %d.", len(compound.Statements))
+		}
+
+		statement := compound.Statements[0]
+
+		if statement.String() != expectation.output {
+			t.Fatalf(
+				"Expected %q, got %q.",
+				expectation.output,
+				statement.String(),
+			)
+		}
+
+		expectJsonRoundTrip(t, expectation.input, compound)
+	}
+}
+
+func (*parserHelpers) expectFirstErrors(
+	t *testing.T,
+	expectations []struct {
+		input   string
+		message string
+	},
+) {
+	for _, expectation := range expectations {
+		lexer := monkey.NewLexer(expectation.input)
+		parser := monkey.NewParser(lexer)
+		compound := parser.Parse()
+
+		if len(parser.Errors()) == 0 {
+			t.Fatalf("Expected errors for %q, got none.", expectation.input)
+		}
+
+		expectJsonRoundTrip(t, expectation.input, compound)
+
+		if parser.Errors()[0].Error() != expectation.message {
+			t.Fatalf(
+				"Expected %q, got %q.",
+				expectation.message,
+				parser.Errors()[0].Error(),
+			)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	input := `let a = 5;
 let b = true;
@@ -449,3 +508,729 @@ func TestFunctionDefinitions(t *testing.T) {
 		}
 	}
 }
+
+func TestPipeExpressions(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"xs |> sum", "(xs |> sum);"},
+		{
+			"xs |> filter(isEven) |> map(square) |> sum",
+			"(((xs |> filter(isEven)) |> map(square)) |> sum);",
+		},
+		{"a + b |> f(c)", "((a + b) |> f(c));"},
+		{"a == b |> f", "((a == b) |> f);"},
+		{"let total = xs |> sum;", "let total = (xs |> sum);"},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectOutputs(t, expectations)
+}
+
+func TestPipeDesugar(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"xs |> sum", "sum(xs)"},
+		{"xs |> filter(isEven)", "filter(xs, isEven)"},
+		{"xs |> map(square) |> sum", "sum((xs |> map(square)))"},
+		{"1 + 2 |> add(3, 4)", "add((1 + 2), 3, 4)"},
+	}
+
+	helpers := &parserHelpers{}
+
+	for _, expectation := range expectations {
+		lexer := monkey.NewLexer(expectation.input)
+		parser := monkey.NewParser(lexer)
+		compound := parser.Parse()
+
+		expressionStatement := helpers.expectExpressionStatement(
+			t,
+			compound.Statements[0],
+		)
+		if expressionStatement == nil {
+			return
+		}
+
+		pipe, ok := expressionStatement.Expression.(*monkey.AstPipeExpression)
+		if !ok {
+			t.Fatal("Given expression is not a pipe expression.")
+		}
+
+		call := pipe.Desugar()
+		if call == nil {
+			t.Fatalf("Expected %q to desugar into a call.", expectation.input)
+		}
+
+		if call.String() != expectation.output {
+			t.Fatalf("Expected %q, got %q.", expectation.output, call.String())
+		}
+	}
+
+	lexer := monkey.NewLexer("xs |> fn (x) { x }")
+	parser := monkey.NewParser(lexer)
+	compound := parser.Parse()
+
+	expressionStatement := helpers.expectExpressionStatement(
+		t,
+		compound.Statements[0],
+	)
+	pipe := expressionStatement.Expression.(*monkey.AstPipeExpression)
+	if pipe.Desugar() != nil {
+		t.Fatal("Expected a function literal operand not to desugar.")
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{`"hello world"`, `"hello world";`},
+		{`let greeting = "hi";`, `let greeting = "hi";`},
+		{`concat("a", "b")`, `concat("a", "b");`},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectOutputs(t, expectations)
+}
+
+func TestMatchExpressions(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{
+			`match (value) { 0 => "zero", [x, y] => x + y, {"kind": k} => k, n if n > 10 => "big", _ => "other" }`,
+			`match (value) { 0 => "zero", [x, y] => (x + y), {"kind": k} => k, n if (n > 10) => "big", _ => "other" };`,
+		},
+		{
+			`match (x) { -1 => false, true => 1, "s" => 2, _ => 3, }`,
+			`match (x) { -1 => false, true => 1, "s" => 2, _ => 3 };`,
+		},
+		{
+			`match (p) { [[a, _], {"x": 0, "y": y}] => y, other => other }`,
+			`match (p) { [[a, _], {"x": 0, "y": y}] => y, other => other };`,
+		},
+		{
+			`match (xs) { [head, ...tail] => head, [] => 0 }`,
+			`match (xs) { [head, ...tail] => head, [] => 0 };`,
+		},
+		{
+			`let size = match (xs) { [] => 0, [_] => 1, _ => 2 };`,
+			`let size = match (xs) { [] => 0, [_] => 1, _ => 2 };`,
+		},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectOutputs(t, expectations)
+}
+
+func TestMatchExhaustivenessWarnings(t *testing.T) {
+	expectations := []struct {
+		input    string
+		warnings int
+	}{
+		{`match (x) { 0 => 1, _ => 2 }`, 0},
+		{`match (x) { 0 => 1, n => n }`, 0},
+		{`match (x) { 0 => 1, 1 => 2 }`, 1},
+		{`match (x) { n if n > 0 => 1, _ if n < 0 => 2 }`, 1},
+		{`match (x) { [a] => a, {"k": v} => v }`, 1},
+	}
+
+	for _, expectation := range expectations {
+		lexer := monkey.NewLexer(expectation.input)
+		parser := monkey.NewParser(lexer)
+		parser.Parse()
+
+		if len(parser.Errors()) != 0 {
+			t.Fatalf("Expected no errors, got %q.", parser.Errors()[0])
+		}
+
+		if len(parser.Warnings()) != expectation.warnings {
+			t.Fatalf(
+				"Expected %d warnings for %q, got %d.",
+				expectation.warnings,
+				expectation.input,
+				len(parser.Warnings()),
+			)
+		}
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	expectations := []struct {
+		input   string
+		message string
+	}{
+		{`match (x) { 0 1 }`, `expected Fat Arrow, got "1"`},
+		{`match x { _ => 1 }`, `expected Open Paren, got "x"`},
+		{`match (x) { + => 1 }`, `expected a pattern, got "+"`},
+		{`match (x) { [a, b => 1 }`, `expected Close Bracket, got "=>"`},
+		{`match (x) { {1: a} => 1 }`, `expected a string or identifier key in hash pattern, got "1"`},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectFirstErrors(t, expectations)
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"let [first, second, ...rest] = xs;", "let [first, second, ...rest] = xs;"},
+		{"let {name, age: years} = person;", "let {name, age: years} = person;"},
+		{`let {"key": value} = hash;`, `let {"key": value} = hash;`},
+		{
+			"let [a, [b, c], {inner: {deep}}] = nested",
+			"let [a, [b, c], {inner: {deep}}] = nested;",
+		},
+		{"let [_, second] = pair;", "let [_, second] = pair;"},
+		{"let {name: name} = person;", "let {name} = person;"},
+		{"let [] = empty;", "let [] = empty;"},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectOutputs(t, expectations)
+
+	lexer := monkey.NewLexer("let {name, age: years} = person;")
+	parser := monkey.NewParser(lexer)
+	compound := parser.Parse()
+
+	letStatement, ok := compound.Statements[0].(*monkey.AstLetStatement)
+	if !ok {
+		t.Fatal("Given statement is not a let statement.")
+	}
+
+	if letStatement.Identifier != nil {
+		t.Fatal("Expected destructuring let statement to have no identifier.")
+	}
+
+	if _, ok := letStatement.Pattern.(*monkey.AstHashPattern); !ok {
+		t.Fatal("Expected let statement pattern to be a hash pattern.")
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	expectations := []struct {
+		input   string
+		message string
+	}{
+		{"let [a, a] = xs;", `duplicate binding "a" in pattern`},
+		{"let [a, [b, ...a]] = xs;", `duplicate binding "a" in pattern`},
+		{"let {name, other: name} = p;", `duplicate binding "name" in pattern`},
+		{"let [...rest, last] = xs;", "rest element must be the last element of an array pattern"},
+		{"let [...1] = xs;", `expected an identifier after "...", got "1"`},
+		{`match (x) { [a, a] => a, _ => 0 }`, `duplicate binding "a" in pattern`},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectFirstErrors(t, expectations)
+}
+
+func TestFunctionParameters(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"fn (a, b = 10, ...rest) { }", "fn (a, b = 10, ...rest) {  };"},
+		{"fn (a = 1 + 2, b = add(1, 2)) { a }", "fn (a = (1 + 2), b = add(1, 2)) { a; };"},
+		{"fn (...args) { args }", "fn (...args) { args; };"},
+		{"fn (a = 1, ...rest) { rest }", "fn (a = 1, ...rest) { rest; };"},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectOutputs(t, expectations)
+
+	lexer := monkey.NewLexer("fn (a, b = 10, ...rest) { }")
+	parser := monkey.NewParser(lexer)
+	compound := parser.Parse()
+
+	expressionStatement := helpers.expectExpressionStatement(
+		t,
+		compound.Statements[0],
+	)
+	functionDefinition := expressionStatement.Expression.(*monkey.AstFunctionDefinition)
+	params := functionDefinition.Params
+
+	if len(params) != 3 {
+		t.Fatalf("Expected 3 params, got %d.", len(params))
+	}
+	if params[0].Default != nil || params[0].Rest {
+		t.Fatal("Expected first param to be required.")
+	}
+	if helpers.expectIntegerLiteral(t, params[1].Default, 10) == nil {
+		return
+	}
+	if !params[2].Rest || params[2].Identifier.Value != "rest" {
+		t.Fatal("Expected last param to be a rest param named \"rest\".")
+	}
+}
+
+func TestSpreadArguments(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"f(...args, 3)", "f(...args, 3);"},
+		{"f(1, ...xs, ...ys)", "f(1, ...xs, ...ys);"},
+		{"f(...g(a))", "f(...g(a));"},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectOutputs(t, expectations)
+}
+
+func TestFunctionParameterErrors(t *testing.T) {
+	expectations := []struct {
+		input   string
+		message string
+	}{
+		{
+			"fn (a = 1, b) { }",
+			`required parameter "b" cannot follow a parameter with a default value`,
+		},
+		{"fn (...a, b) { }", `rest parameter "a" must be the last parameter`},
+		{"fn (...a, ...b) { }", `rest parameter "a" must be the last parameter`},
+		{"fn (...a = 1) { }", `rest parameter "a" cannot have a default value`},
+		{"fn (1) { }", `expected a parameter name, got "1"`},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectFirstErrors(t, expectations)
+}
+
+func TestFunctionDeclarations(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"fn add(a, b) { return a + b; }", "fn add(a, b) { return (a + b); }"},
+		{"fn noop() {};", "fn noop() {  }"},
+		{"fn greet(name = \"you\") { name }", "fn greet(name = \"you\") { name; }"},
+		{"fn (a) { a }", "fn (a) { a; };"},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectOutputs(t, expectations)
+
+	lexer := monkey.NewLexer("fn add(a, b) { a + b }")
+	parser := monkey.NewParser(lexer)
+	compound := parser.Parse()
+
+	declaration, ok := compound.Statements[0].(*monkey.AstFunctionDeclaration)
+	if !ok {
+		t.Fatal("Given statement is not a function declaration.")
+	}
+
+	if declaration.Name.Value != "add" {
+		t.Fatalf("Expected declaration name to be \"add\", got %q.", declaration.Name.Value)
+	}
+
+	if len(declaration.Function.Params) != 2 {
+		t.Fatalf("Expected 2 params, got %d.", len(declaration.Function.Params))
+	}
+}
+
+func TestRangeExpressions(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"0..10", "(0..10);"},
+		{"0..=10", "(0..=10);"},
+		{"1..2", "(1..2);"},
+		{"0..n + 1", "(0..(n + 1));"},
+		{"a * 2..=b - 1", "((a * 2)..=(b - 1));"},
+		{"x in xs", "(x in xs);"},
+		{"x in 0..10", "(x in (0..10));"},
+		{"x + 1 in 0..=n == true", "(((x + 1) in (0..=n)) == true);"},
+		{"let r = 0..len(xs);", "let r = (0..len(xs));"},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectOutputs(t, expectations)
+
+	lexer := monkey.NewLexer("0..=10")
+	parser := monkey.NewParser(lexer)
+	compound := parser.Parse()
+
+	expressionStatement := helpers.expectExpressionStatement(
+		t,
+		compound.Statements[0],
+	)
+	rangeExpression, ok := expressionStatement.Expression.(*monkey.AstRangeExpression)
+	if !ok {
+		t.Fatal("Given expression is not a range expression.")
+	}
+	if !rangeExpression.Inclusive {
+		t.Fatal("Expected range expression to be inclusive.")
+	}
+	if helpers.expectIntegerLiteral(t, rangeExpression.Start, 0) == nil {
+		return
+	}
+	if helpers.expectIntegerLiteral(t, rangeExpression.Stop, 10) == nil {
+		return
+	}
+}
+
+func TestIfExpressions(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"if (x < y) { x }", "if ((x < y)) { x; };"},
+		{"if (x) { x } else { y }", "if (x) { x; } else { y; };"},
+		{"let max = if (a > b) { a } else { b };", "let max = if ((a > b)) { a; } else { b; };"},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectOutputs(t, expectations)
+}
+
+func TestMacroLiterals(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"macro(x, y) { x + y; }", "macro (x, y) { (x + y); };"},
+		{"let m = macro() { quote(1) };", "let m = macro () { quote(1); };"},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectOutputs(t, expectations)
+}
+
+func TestImportExportStatements(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{`import "lib/strings" as str;`, `import "lib/strings" as str;`},
+		{`import "prelude"`, `import "prelude";`},
+		{"export let helper = fn() { 1 };", "export let helper = fn () { 1; };"},
+		{"export let [a, b] = pair;", "export let [a, b] = pair;"},
+		{"export fn helper(x) { x }", "export fn helper(x) { x; }"},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectOutputs(t, expectations)
+}
+
+func TestImportExportErrors(t *testing.T) {
+	expectations := []struct {
+		input   string
+		message string
+	}{
+		{"import strings;", `expected an import path string, got "strings"`},
+		{`import "lib" as 1;`, `expected an import alias, got "1"`},
+		{
+			"export 1 + 2;",
+			`only let statements and function declarations can be exported, got "1"`,
+		},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectFirstErrors(t, expectations)
+}
+
+func TestAutomaticSemicolonInsertion(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"let a = b\n(c)", "let a = b;(c);"},
+		{"let a = b\n-c", "let a = b;(-c);"},
+		{"let a = b\n!c", "let a = b;(!c);"},
+		{"let a = 1 +\n  2\n", "let a = (1 + 2);"},
+		{"f(a)\n(b)", "f(a);(b);"},
+		{"add(\n  1,\n  2\n)\n", "add(1, 2);"},
+		{"add(\n  1,\n  2,\n)\n", "add(1, 2);"},
+		{"fn f() {\n  return\n}\n", "fn f() { return; }"},
+		{"fn f() {\n  return\n  1\n}\n", "fn f() { return;1; }"},
+		{"xs |>\n  filter(isEven) |>\n  sum\n", "((xs |> filter(isEven)) |> sum);"},
+		{
+			"if (a) {\n  b\n} else {\n  c\n}\nd",
+			"if (a) { b; } else { c; };d;",
+		},
+		{
+			"let f = fn(a,\n  b) {\n  a\n  b\n}\nf(1, 2)",
+			"let f = fn (a, b) { a;b; };f(1, 2);",
+		},
+		{
+			"match (x) {\n  0 => \"zero\",\n  _ => \"other\"\n}\n",
+			"match (x) { 0 => \"zero\", _ => \"other\" };",
+		},
+		{"let {\n  name,\n  age\n} = person\n", "let {name, age} = person;"},
+		{"1\n\n\n2\n", "1;2;"},
+	}
+
+	for _, expectation := range expectations {
+		lexer := monkey.NewLexer(expectation.input)
+		parser := monkey.NewParser(lexer)
+		compound := parser.Parse()
+
+		if compound.String() != expectation.output {
+			t.Fatalf(
+				"Expected %q for %q, got %q.",
+				expectation.output,
+				expectation.input,
+				compound.String(),
+			)
+		}
+	}
+}
+
+func TestGroupedExpressions(t *testing.T) {
+	expectations := []struct {
+		input     string
+		preserved string
+		collapsed string
+	}{
+		{"(a + b)", "(a + b);", "(a + b);"},
+		{"a + b", "(a + b);", "(a + b);"},
+		{"((a))", "(a);", "a;"},
+		{"-(5 + 5)", "(-(5 + 5));", "(-(5 + 5));"},
+		{"f((1), 2)", "f((1), 2);", "f(1, 2);"},
+	}
+
+	for _, expectation := range expectations {
+		lexer := monkey.NewLexer(expectation.input)
+		parser := monkey.NewParser(lexer)
+		compound := parser.Parse()
+
+		if compound.String() != expectation.preserved {
+			t.Fatalf("Expected %q, got %q.", expectation.preserved, compound.String())
+		}
+
+		lexer = monkey.NewLexer(expectation.input)
+		parser = monkey.NewParser(lexer)
+		parser.SetCollapseGroups(true)
+		compound = parser.Parse()
+
+		if compound.String() != expectation.collapsed {
+			t.Fatalf("Expected %q, got %q.", expectation.collapsed, compound.String())
+		}
+	}
+
+	lexer := monkey.NewLexer("(a + b)")
+	parser := monkey.NewParser(lexer)
+	compound := parser.Parse()
+
+	helpers := &parserHelpers{}
+	expressionStatement := helpers.expectExpressionStatement(t, compound.Statements[0])
+	grouped, ok := expressionStatement.Expression.(*monkey.AstGroupedExpression)
+	if !ok {
+		t.Fatal("Given expression is not a grouped expression.")
+	}
+	if _, ok := grouped.Expression.(*monkey.AstInfixExpression); !ok {
+		t.Fatal("Expected grouped expression to hold an infix expression.")
+	}
+}
+
+func TestGroupedExpressionErrors(t *testing.T) {
+	expectations := []struct {
+		input   string
+		message string
+	}{
+		{"(a + b", `expected Close Paren, got "\x00"`},
+		{"(a b)", `expected Close Paren, got "b"`},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectFirstErrors(t, expectations)
+}
+
+func TestParserErrors(t *testing.T) {
+	expectations := []struct {
+		input   string
+		message string
+	}{
+		{"add(1, 2", `expected Close Paren, got "\x00"`},
+		{"add(1 2)", `expected Close Paren, got "2"`},
+		{"fn (a", `expected Close Paren, got "\x00"`},
+		{"fn (a) { a", `expected Close Brace, got "\x00"`},
+		{"fn a", `expected Open Paren, got "\x00"`},
+		{"let = 5", `expected a name or a pattern after "let", got "="`},
+		{"let x 5", `expected Assignment, got "5"`},
+		{"let x = ;", `unexpected ";", expected an expression`},
+		{"return )", `unexpected ")", expected an expression`},
+		{"1 + @", `illegal token "@"`},
+		{"99999999999999999999", `could not parse "99999999999999999999" as an integer`},
+		{"xs\n|> sum", `unexpected "|>", expected an expression`},
+		{"a }", `unexpected "}"`},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectFirstErrors(t, expectations)
+}
+
+func TestParserNestingLimit(t *testing.T) {
+	message := "input is nested too deeply"
+	expectations := []struct {
+		input   string
+		message string
+	}{
+		{strings.Repeat("(", 100000), message},
+		{strings.Repeat("-", 100000) + "1", message},
+		{"let " + strings.Repeat("[", 100000) + " = xs", message},
+		{strings.Repeat("fn a() {", monkey.MAX_NESTING_DEPTH+1), message},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectFirstErrors(t, expectations)
+}
+
+func TestPowerExpressions(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"2 ** 3", "(2 ** 3);"},
+		{"2 ** 3 ** 2", "(2 ** (3 ** 2));"},
+		{"2 ** 3 ** 2 == 2 ** 9", "((2 ** (3 ** 2)) == (2 ** 9));"},
+		{"-2 ** 2", "(-(2 ** 2));"},
+		{"-2 ** 2 == -4", "((-(2 ** 2)) == (-4));"},
+		{"!a ** b", "(!(a ** b));"},
+		{"2 ** -2", "(2 ** (-2));"},
+		{"2 ** -2 ** 2", "(2 ** (-(2 ** 2)));"},
+		{"a * b ** c", "(a * (b ** c));"},
+		{"a ** b * c", "((a ** b) * c);"},
+		{"a + b ** c ** d - e", "((a + (b ** (c ** d))) - e);"},
+		{"a / b / c", "((a / b) / c);"},
+		{"a - b - c", "((a - b) - c);"},
+		{"(2 ** 3) ** 2", "((2 ** 3) ** 2);"},
+		{"f(2) ** g(3) ** 2", "(f(2) ** (g(3) ** 2));"},
+		{"0..2 ** n", "(0..(2 ** n));"},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectOutputs(t, expectations)
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let xs: [string] = ys;", "let xs: [string] = ys;"},
+		{"let [a, b]: [int] = xs;", "let [a, b]: [int] = xs;"},
+		{
+			"let f: fn(int, [bool]) -> string = g;",
+			"let f: fn(int, [bool]) -> string = g;",
+		},
+		{"let f: fn() = g;", "let f: fn() = g;"},
+		{
+			"fn (a: int, b: string) -> bool { a }",
+			"fn (a: int, b: string) -> bool { a; };",
+		},
+		{"fn (a: int = 1, ...rest: [int]) { a }", "fn (a: int = 1, ...rest: [int]) { a; };"},
+		{"fn () -> [[int]] { xs }", "fn () -> [[int]] { xs; };"},
+		{"fn add(a: int, b: int) -> int { a + b }", "fn add(a: int, b: int) -> int { (a + b); }"},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectOutputs(t, expectations)
+}
+
+func TestTypeAnnotationErrors(t *testing.T) {
+	expectations := []struct {
+		input   string
+		message string
+	}{
+		{"let x: = 5;", `expected a type, got "="`},
+		{"let x: 5 = 5;", `expected a type, got "5"`},
+		{"let x: [int = 5;", `expected Close Bracket, got "="`},
+		{"fn (a:) { a }", `expected a type, got ")"`},
+		{"fn () -> { 1 }", `expected a type, got "{"`},
+		{"let x: int 5;", `expected Assignment, got "5"`},
+	}
+
+	helpers := &parserHelpers{}
+	helpers.expectFirstErrors(t, expectations)
+}
+
+func TestDocComments(t *testing.T) {
+	input := `/// Adds two numbers.
+///
+/// Both must be integers.
+fn add(a, b) { a + b }
+
+// not documentation
+let x = 1
+
+/// The answer.
+export let answer = fn () {
+  /// Inner.
+  let y = 42
+  y
+}`
+
+	parser := monkey.NewParser(monkey.NewLexer(input))
+	program := parser.Parse()
+
+	if len(parser.Errors()) > 0 || len(parser.Warnings()) > 0 {
+		t.Fatalf("Unexpected errors %v and warnings %v.", parser.Errors(), parser.Warnings())
+	}
+
+	declaration := program.Statements[0].(*monkey.AstFunctionDeclaration)
+	if declaration.Doc == nil ||
+		declaration.Doc.Text() != "Adds two numbers.\n\nBoth must be integers." {
+		t.Fatalf("Unexpected doc on %q: %v", declaration.String(), declaration.Doc)
+	}
+
+	if declaration.Doc.String() != "/// Adds two numbers.\n///\n/// Both must be integers." {
+		t.Fatalf("Unexpected doc source %q.", declaration.Doc.String())
+	}
+
+	letStatement := program.Statements[1].(*monkey.AstLetStatement)
+	if letStatement.Doc != nil {
+		t.Fatalf("Expected no doc on %q, got %q.", letStatement.String(), letStatement.Doc.Text())
+	}
+
+	exported := program.Statements[2].(*monkey.AstExportStatement).Statement.(*monkey.AstLetStatement)
+	if exported.Doc == nil || exported.Doc.Text() != "The answer." {
+		t.Fatalf("Unexpected doc on %q: %v", exported.String(), exported.Doc)
+	}
+
+	function := exported.Value.(*monkey.AstFunctionDefinition)
+	inner := function.Body.Statements[0].(*monkey.AstLetStatement)
+	if inner.Doc == nil || inner.Doc.Text() != "Inner." {
+		t.Fatalf("Unexpected doc on %q: %v", inner.String(), inner.Doc)
+	}
+}
+
+func TestDanglingDocComments(t *testing.T) {
+	expectations := []struct {
+		input  string
+		line   int
+		column int
+	}{
+		{"/// Nothing follows.", 1, 1},
+		{"/// Not a declaration.\nx + 1", 1, 1},
+		{"let x = 1 /// trailing\nlet y = 2", 1, 11},
+		{"fn f() {\n  x\n  /// last\n}", 3, 3},
+		{"/// Twice.\nreturn 1\n/// Once.\nlet x = 1", 1, 1},
+	}
+
+	for _, expectation := range expectations {
+		parser := monkey.NewParser(monkey.NewLexer(expectation.input))
+		parser.Parse()
+
+		if len(parser.Errors()) > 0 {
+			t.Fatalf("Unexpected error for %q: %s", expectation.input, parser.Errors()[0])
+		}
+
+		if len(parser.Warnings()) != 1 {
+			t.Fatalf("Expected 1 warning for %q, got %v.", expectation.input, parser.Warnings())
+		}
+
+		warning := parser.Warnings()[0]
+		expected := "dangling doc comment, expected a let statement or a function declaration after it"
+		expectErrorAt(t, warning, warning.Token, expected, expectation.line, expectation.column)
+	}
+}
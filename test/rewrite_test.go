@@ -0,0 +1,155 @@
+package test
+
+import (
+	"fmt"
+	"monkey/monkey"
+	"testing"
+)
+
+func foldConstants(node monkey.AstNode) monkey.AstNode {
+	infix, ok := node.(*monkey.AstInfixExpression)
+	if !ok || infix.Operator != "+" {
+		return node
+	}
+
+	left, leftOk := infix.Left.(*monkey.AstIntegerLiteral)
+	right, rightOk := infix.Right.(*monkey.AstIntegerLiteral)
+	if !leftOk || !rightOk {
+		return node
+	}
+
+	value := left.Value + right.Value
+	return &monkey.AstIntegerLiteral{
+		Token: &monkey.Token{Type: monkey.TOKEN_INTEGER, Literal: fmt.Sprint(value)},
+		Value: value,
+	}
+}
+
+func TestModify(t *testing.T) {
+	expectations := []struct {
+		input  string
+		output string
+	}{
+		{"1 + 2", "3;"},
+		{"1 + 2 + 3", "6;"},
+		{"let x = 1 + 2 + a", "let x = (3 + a);"},
+		{"f(1 + 1, g(2 + 2))", "f(2, g(4));"},
+		{"fn (a = 1 + 1) { return 2 + 2 }", "fn (a = 2) { return 4; };"},
+		{"-(1 + 1)", "(-2);"},
+		{"if (1 + 1) { 2 + 2 } else { 3 + 3 }", "if (2) { 4; } else { 6; };"},
+		{"match (1 + 1) { 2 if 0 + 1 => 1 + 2 }", "match (2) { 2 if 1 => 3 };"},
+		{"xs |> f(1 + 1)", "(xs |> f(2));"},
+		{"(1 + 1)..=(2 + 2)", "(2..=4);"},
+	}
+
+	for _, expectation := range expectations {
+		parser := monkey.NewParser(monkey.NewLexer(expectation.input))
+		parser.SetCollapseGroups(true)
+		program := parser.Parse()
+
+		modified := monkey.Modify(program, foldConstants).(*monkey.AstCompound)
+
+		if modified != program {
+			t.Fatalf("Expected Modify to rewrite %q in place.", expectation.input)
+		}
+
+		if program.Statements[0].String() != expectation.output {
+			t.Fatalf("Expected %q, got %q.", expectation.output, program.Statements[0].String())
+		}
+	}
+}
+
+func TestModifyReplacesTheRoot(t *testing.T) {
+	parser := monkey.NewParser(monkey.NewLexer("1 + 2"))
+	expression := parser.Parse().Statements[0].(*monkey.AstExpressionStatement).Expression
+
+	modified := monkey.Modify(expression, foldConstants)
+
+	if modified.String() != "3" {
+		t.Fatalf("Expected 3, got %q.", modified.String())
+	}
+}
+
+func TestApplyCursor(t *testing.T) {
+	program := parseProgram(t, "let a = 1\nlog(a)\nlet b = f(a, c)\nlog(b)\nreturn b")
+
+	names := []string{}
+	monkey.Apply(program, func(cursor *monkey.Cursor) bool {
+		if identifier, ok := cursor.Node().(*monkey.AstIdentifier); ok && identifier.Value == "c" {
+			_, isCall := cursor.Parent().(*monkey.AstFunctionCall)
+			if !isCall || cursor.Name() != "Arguments" || cursor.Index() != 1 {
+				t.Fatalf(
+					"Unexpected cursor %T.%s[%d] for c.",
+					cursor.Parent(),
+					cursor.Name(),
+					cursor.Index(),
+				)
+			}
+		}
+
+		statement, ok := cursor.Node().(*monkey.AstExpressionStatement)
+		if !ok {
+			return true
+		}
+
+		// drop every log(...) statement, and trace every other one
+		call, ok := statement.Expression.(*monkey.AstFunctionCall)
+		if ok && call.Identifier.Value == "log" {
+			names = append(names, call.Arguments[0].String())
+			cursor.Delete()
+			return false
+		}
+		return true
+	}, func(cursor *monkey.Cursor) bool {
+		letStatement, ok := cursor.Node().(*monkey.AstLetStatement)
+		if ok {
+			trace := parseProgram(t, "trace(\""+letStatement.Identifier.Value+"\")").Statements[0]
+			cursor.InsertBefore(trace)
+			cursor.InsertAfter(parseProgram(t, "done()").Statements[0])
+		}
+		return true
+	})
+
+	expected := `trace("a");let a = 1;done();trace("b");let b = f(a, c);done();return b;`
+	if program.String() != expected {
+		t.Fatalf("Expected %q, got %q.", expected, program.String())
+	}
+
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Fatalf("Expected to delete log(a) and log(b), got %v.", names)
+	}
+}
+
+func TestApplyStops(t *testing.T) {
+	program := parseProgram(t, "a; b; c; d")
+
+	visited := []string{}
+	monkey.Apply(program, nil, func(cursor *monkey.Cursor) bool {
+		if identifier, ok := cursor.Node().(*monkey.AstIdentifier); ok {
+			visited = append(visited, identifier.Value)
+			return identifier.Value != "b"
+		}
+		return true
+	})
+
+	if len(visited) != 2 {
+		t.Fatalf("Expected to stop after b, visited %v.", visited)
+	}
+}
+
+func TestApplyReplacePanicsOnMismatch(t *testing.T) {
+	program := parseProgram(t, "let x = 1")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a statement in place of an expression to panic.")
+		}
+	}()
+
+	monkey.Apply(program, func(cursor *monkey.Cursor) bool {
+		if _, ok := cursor.Node().(*monkey.AstIntegerLiteral); ok {
+			cursor.Replace(program.Statements[0])
+		}
+		return true
+	}, nil)
+}
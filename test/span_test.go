@@ -0,0 +1,177 @@
+package test
+
+import (
+	"monkey/monkey"
+	"testing"
+)
+
+func TestNodeSpans(t *testing.T) {
+	tests := []struct {
+		input    string
+		selector func(program *monkey.AstCompound) monkey.AstNode
+		expected string
+	}{
+		{
+			"x + y * 2",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstExpressionStatement).Expression
+			},
+			"x + y * 2",
+		},
+		{
+			"a * (b + c)",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstExpressionStatement).Expression.(*monkey.AstInfixExpression).Right
+			},
+			"(b + c)",
+		},
+		{
+			"let f = fn(a, b) {\n  a + b\n}",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstLetStatement).Value
+			},
+			"fn(a, b) {\n  a + b\n}",
+		},
+		{
+			"let f = fn(a, b) {\n  a + b\n}",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0]
+			},
+			"let f = fn(a, b) {\n  a + b\n}",
+		},
+		{
+			"fn add(a: int, b = 1) -> int { a + b }",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0]
+			},
+			"fn add(a: int, b = 1) -> int { a + b }",
+		},
+		{
+			"fn add(a: int, b = 1) -> int { a + b }",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstFunctionDeclaration).Function.Params[1]
+			},
+			"b = 1",
+		},
+		{
+			`add(1, "two")`,
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstExpressionStatement).Expression
+			},
+			`add(1, "two")`,
+		},
+		{
+			`add(1, "two")`,
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstExpressionStatement).Expression.(*monkey.AstFunctionCall).Arguments[1]
+			},
+			`"two"`,
+		},
+		{
+			"xs |> map(f)",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstExpressionStatement).Expression
+			},
+			"xs |> map(f)",
+		},
+		{
+			"xs |> map(f)",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstExpressionStatement).Expression.(*monkey.AstPipeExpression).Desugar()
+			},
+			"xs |> map(f)",
+		},
+		{
+			"xs |> sum",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstExpressionStatement).Expression.(*monkey.AstPipeExpression).Desugar()
+			},
+			"xs |> sum",
+		},
+		{
+			"if (x) { 1 } else { 2 }",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstExpressionStatement).Expression
+			},
+			"if (x) { 1 } else { 2 }",
+		},
+		{
+			"match (x) { [a, ...rest] => a, {name} => name, _ => 0 }",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstExpressionStatement).Expression
+			},
+			"match (x) { [a, ...rest] => a, {name} => name, _ => 0 }",
+		},
+		{
+			"match (x) { [a, ...rest] => a, {name} => name, _ => 0 }",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstExpressionStatement).Expression.(*monkey.AstMatchExpression).Arms[0]
+			},
+			"[a, ...rest] => a",
+		},
+		{
+			"let g: fn([int]) -> bool = f",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstLetStatement).Type
+			},
+			"fn([int]) -> bool",
+		},
+		{
+			"1..10",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program.Statements[0].(*monkey.AstExpressionStatement).Expression
+			},
+			"1..10",
+		},
+		{
+			"\n  let a = 1\n  a\n",
+			func(program *monkey.AstCompound) monkey.AstNode {
+				return program
+			},
+			"let a = 1\n  a",
+		},
+	}
+
+	for _, test := range tests {
+		node := test.selector(parseProgram(t, test.input))
+		actual := test.input[node.Pos().Offset:node.End().Offset]
+		if actual != test.expected {
+			t.Errorf("Wrong span for %q: expected %q, got %q", test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestNodeSpanPositions(t *testing.T) {
+	input := "let f = fn(a) {\n  a\n}"
+	span := monkey.NodeSpan(parseProgram(t, input).Statements[0].(*monkey.AstLetStatement).Value)
+
+	if span.Start.Line != 1 || span.Start.Column != 9 {
+		t.Errorf("Wrong start: expected 1:9, got %d:%d", span.Start.Line, span.Start.Column)
+	}
+	if span.End.Line != 3 || span.End.Column != 2 {
+		t.Errorf("Wrong end: expected 3:2, got %d:%d", span.End.Line, span.End.Column)
+	}
+}
+
+func TestNodeSpansNest(t *testing.T) {
+	parents := []monkey.AstNode{}
+	monkey.Inspect(parseProgram(t, syntaxSample), func(node monkey.AstNode) bool {
+		if node == nil {
+			parents = parents[:len(parents)-1]
+			return true
+		}
+
+		if node.Pos().Offset > node.End().Offset {
+			t.Errorf("Span of %T %q ends before it starts", node, node.String())
+		}
+		if len(parents) > 0 {
+			parent := parents[len(parents)-1]
+			if _, ok := node.(*monkey.AstDocComment); !ok && (node.Pos().Offset < parent.Pos().Offset || node.End().Offset > parent.End().Offset) {
+				t.Errorf("Span of %T %q is outside of its parent %T", node, node.String(), parent)
+			}
+		}
+
+		parents = append(parents, node)
+		return true
+	})
+}
@@ -0,0 +1,168 @@
+package test
+
+import (
+	"fmt"
+	"monkey/monkey"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestWalkVisitsEveryNodeKind(t *testing.T) {
+	kinds := map[string]bool{}
+	monkey.Inspect(parseProgram(t, syntaxSample), func(node monkey.AstNode) bool {
+		if node != nil {
+			kinds[fmt.Sprintf("%T", node)] = true
+		}
+		return true
+	})
+
+	expected := []string{
+		"*monkey.AstArrayPattern",
+		"*monkey.AstArrayType",
+		"*monkey.AstBooleanLiteral",
+		"*monkey.AstCompound",
+		"*monkey.AstDocComment",
+		"*monkey.AstExportStatement",
+		"*monkey.AstExpressionStatement",
+		"*monkey.AstFunctionCall",
+		"*monkey.AstFunctionDeclaration",
+		"*monkey.AstFunctionDefinition",
+		"*monkey.AstFunctionType",
+		"*monkey.AstGroupedExpression",
+		"*monkey.AstHashPattern",
+		"*monkey.AstHashPatternPair",
+		"*monkey.AstIdentifier",
+		"*monkey.AstIdentifierPattern",
+		"*monkey.AstIfExpression",
+		"*monkey.AstImportStatement",
+		"*monkey.AstInfixExpression",
+		"*monkey.AstIntegerLiteral",
+		"*monkey.AstLetStatement",
+		"*monkey.AstLiteralPattern",
+		"*monkey.AstMacroLiteral",
+		"*monkey.AstMatchArm",
+		"*monkey.AstMatchExpression",
+		"*monkey.AstNamedType",
+		"*monkey.AstParameter",
+		"*monkey.AstPipeExpression",
+		"*monkey.AstPrefixExpression",
+		"*monkey.AstRangeExpression",
+		"*monkey.AstRestPattern",
+		"*monkey.AstReturnStatement",
+		"*monkey.AstSpreadExpression",
+		"*monkey.AstStringLiteral",
+		"*monkey.AstWildcardPattern",
+	}
+
+	visited := []string{}
+	for kind := range kinds {
+		visited = append(visited, kind)
+	}
+	sort.Strings(visited)
+
+	if !reflect.DeepEqual(visited, expected) {
+		t.Fatalf("Expected to visit\n%v\ngot\n%v", expected, visited)
+	}
+}
+
+func TestWalkOrder(t *testing.T) {
+	expectations := []struct {
+		input string
+		order []string
+	}{
+		{"let x: int = a + b", []string{"x", "int", "a", "b"}},
+		{"let [p, ...q] = -r", []string{"p", "q", "r"}},
+		{"fn f(b, a: int = d) -> bool { c }", []string{"f", "b", "a", "int", "d", "bool", "c"}},
+		{"f(a, g(b), c)", []string{"f", "a", "g", "b", "c"}},
+		{"a |> b(c)", []string{"a", "b", "c"}},
+		{"if (a) { b } else { c }", []string{"a", "b", "c"}},
+		{"match (a) { b if c => d, {e: f} => g }", []string{"a", "b", "c", "d", "e", "f", "g"}},
+		{"let f: fn(a, [b]) -> c = d", []string{"f", "a", "b", "c", "d"}},
+		{"import \"a\" as b", []string{"b"}},
+		{"a..b", []string{"a", "b"}},
+	}
+
+	for _, expectation := range expectations {
+		order := []string{}
+		monkey.Inspect(parseProgram(t, expectation.input), func(node monkey.AstNode) bool {
+			switch node := node.(type) {
+			case *monkey.AstIdentifier:
+				order = append(order, node.Value)
+			case *monkey.AstNamedType:
+				order = append(order, node.Name)
+			}
+			return true
+		})
+
+		if !reflect.DeepEqual(order, expectation.order) {
+			t.Fatalf("Expected %q to visit %v, got %v.", expectation.input, expectation.order, order)
+		}
+	}
+}
+
+type depthVisitor struct {
+	depth    *int
+	maxDepth *int
+}
+
+func (visitor depthVisitor) Visit(node monkey.AstNode) monkey.Visitor {
+	if node == nil {
+		*visitor.depth -= 1
+		return nil
+	}
+	*visitor.depth += 1
+	*visitor.maxDepth = max(*visitor.maxDepth, *visitor.depth)
+	return visitor
+}
+
+func TestWalkPairsEveryVisitWithNil(t *testing.T) {
+	depth, maxDepth := 0, 0
+	program := parseProgram(t, "let x = fn (a) { a * (b + c) }")
+
+	monkey.Walk(depthVisitor{&depth, &maxDepth}, program)
+
+	if depth != 0 {
+		t.Fatalf("Expected every visit to be closed, %d are open.", depth)
+	}
+
+	// compound, let, function, compound, statement, infix, group, infix, identifier
+	if maxDepth != 9 {
+		t.Fatalf("Expected a depth of 9, got %d.", maxDepth)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := parseProgram(t, "let f = fn (a) { b }; c")
+
+	identifiers := []string{}
+	monkey.Inspect(program, func(node monkey.AstNode) bool {
+		if identifier, ok := node.(*monkey.AstIdentifier); ok {
+			identifiers = append(identifiers, identifier.Value)
+		}
+		_, isFunction := node.(*monkey.AstFunctionDefinition)
+		return !isFunction
+	})
+
+	if !reflect.DeepEqual(identifiers, []string{"f", "c"}) {
+		t.Fatalf("Expected [f c], got %v.", identifiers)
+	}
+}
+
+func TestInspectVisitsEveryNodeOnce(t *testing.T) {
+	program := parseProgram(t, "let {name, age: years} = person")
+
+	visits := map[monkey.AstNode]int{}
+	monkey.Inspect(program, func(node monkey.AstNode) bool {
+		if node != nil {
+			visits[node] += 1
+		}
+		return true
+	})
+
+	for node, count := range visits {
+		if count != 1 {
+			t.Fatalf("Expected %T %q to be visited once, got %d visits.", node, node.String(), count)
+		}
+	}
+}